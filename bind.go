@@ -0,0 +1,46 @@
+package cli53
+
+import (
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/miekg/dns"
+)
+
+// ParseBindRecordSets reads a BIND zone file from r and groups its records
+// into route53.ResourceRecordSet the same way Route53 itself does: one
+// RRset per (name, type), multi-value where the file repeats a name/type.
+func ParseBindRecordSets(r io.Reader, origin, filename string) ([]*route53.ResourceRecordSet, error) {
+	grouped := map[rrsetKey]*route53.ResourceRecordSet{}
+	var order []rrsetKey
+
+	zp := dns.NewZoneParser(r, origin, filename)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		name := rr.Header().Name
+		typ := dns.TypeToString[rr.Header().Rrtype]
+		key := rrsetKey{Name: strings.ToLower(name), Type: typ}
+		rrset, exists := grouped[key]
+		if !exists {
+			rrset = &route53.ResourceRecordSet{
+				Name: aws.String(name),
+				Type: aws.String(typ),
+				TTL:  aws.Int64(int64(rr.Header().Ttl)),
+			}
+			grouped[key] = rrset
+			order = append(order, key)
+		}
+		value := strings.TrimPrefix(rr.String(), rr.Header().String())
+		rrset.ResourceRecords = append(rrset.ResourceRecords, &route53.ResourceRecord{Value: aws.String(value)})
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+
+	rrsets := make([]*route53.ResourceRecordSet, 0, len(order))
+	for _, key := range order {
+		rrsets = append(rrsets, grouped[key])
+	}
+	return rrsets, nil
+}
@@ -0,0 +1,76 @@
+package cli53
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// RunApply implements the `cli53 apply` subcommand: read a desired-state
+// BIND zone file, diff it against the domain's live records, print the
+// result as a BIND-line diff, and (unless --dry-run) submit the computed
+// changes through provider.
+func RunApply(args []string, provider Provider, out io.Writer) error {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	file := fs.String("file", "", "desired-state BIND zone file (also accepts -f)")
+	fs.StringVar(file, "f", "", "shorthand for --file")
+	dryRun := fs.Bool("dry-run", false, "print the diff without submitting changes")
+	noPurge := fs.Bool("no-purge", false, "don't delete records present live but absent from the file")
+	filterFlag := fs.String("filter", "", `restrict the diff to these types, e.g. "type=A,MX"`)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: cli53 apply <domain> --file=<zonefile> [--dry-run] [--no-purge] [--filter=type=A,MX]")
+	}
+	domainName := fs.Arg(0)
+	if *file == "" {
+		return fmt.Errorf("apply: --file is required")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return fmt.Errorf("apply: %s", err)
+	}
+	defer f.Close()
+
+	desired, err := ParseBindRecordSets(f, domainName+".", *file)
+	if err != nil {
+		return fmt.Errorf("apply: parsing %s: %s", *file, err)
+	}
+
+	zoneID, err := zoneIDForDomain(provider, domainName)
+	if err != nil {
+		return fmt.Errorf("apply: %s", err)
+	}
+	actual, err := provider.ListRecords(zoneID)
+	if err != nil {
+		return fmt.Errorf("apply: %s", err)
+	}
+
+	differ := &Differ{
+		Origin:  domainName + ".",
+		NoPurge: *noPurge,
+		Types:   parseFilterTypes(*filterFlag),
+	}
+	creates, updates, deletes := differ.Diff(desired, actual)
+	fmt.Fprint(out, DiffText(creates, updates, deletes))
+
+	if *dryRun {
+		return nil
+	}
+	return provider.ApplyChanges(zoneID, Batch(creates, updates, deletes).Changes)
+}
+
+// parseFilterTypes parses a --filter flag of the form "type=A,MX" into the
+// list of record types Differ should restrict itself to. Any other form is
+// treated as no filter.
+func parseFilterTypes(filter string) []string {
+	const prefix = "type="
+	if !strings.HasPrefix(filter, prefix) {
+		return nil
+	}
+	return strings.Split(strings.TrimPrefix(filter, prefix), ",")
+}
@@ -0,0 +1,87 @@
+package cli53
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// RunDiscover implements the `cli53 discover` subcommand: run the
+// requested Sources against domain, resolve what they find, and write a
+// BIND zone file of the results suitable for `cli53 import`.
+func RunDiscover(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("discover", flag.ContinueOnError)
+	sourcesFlag := fs.String("sources", "ct", "comma-separated sources to use: ct,passivedns,bruteforce,zonewalk")
+	wordlist := fs.String("wordlist", "", "wordlist path, required by the bruteforce source")
+	output := fs.String("output", "", "file to write the discovered BIND zone to (also accepts -o)")
+	fs.StringVar(output, "o", "", "shorthand for --output")
+	resolverFixture := fs.String("resolver-fixture", "", "resolve candidates from this JSON fixture instead of live DNS (for tests)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("usage: cli53 discover <domain> [--sources=ct,bruteforce] [--wordlist=file] [--output=file]")
+	}
+	domainName := fs.Arg(0)
+
+	var resolver Resolver = LookupResolver{}
+	if *resolverFixture != "" {
+		fixture, err := LoadFixtureResolver(*resolverFixture)
+		if err != nil {
+			return fmt.Errorf("discover: %s", err)
+		}
+		resolver = fixture
+	}
+
+	sources, err := buildSources(strings.Split(*sourcesFlag, ","), *wordlist)
+	if err != nil {
+		return fmt.Errorf("discover: %s", err)
+	}
+
+	engine := &Engine{Sources: sources, Resolver: resolver}
+	results, err := engine.Run(domainName)
+	if err != nil {
+		return fmt.Errorf("discover: %s", err)
+	}
+
+	if *output != "" {
+		var b strings.Builder
+		for _, rrset := range ToRecordSets(results) {
+			for _, rr := range ConvertRRSetToBind(rrset) {
+				fmt.Fprintln(&b, rr.String())
+			}
+		}
+		if err := ioutil.WriteFile(*output, []byte(b.String()), 0644); err != nil {
+			return fmt.Errorf("discover: writing %s: %s", *output, err)
+		}
+	}
+
+	fmt.Fprintf(out, "%d records discovered\n", len(results))
+	return nil
+}
+
+func buildSources(names []string, wordlist string) ([]Source, error) {
+	var sources []Source
+	for _, name := range names {
+		switch strings.TrimSpace(name) {
+		case "ct":
+			sources = append(sources, &CTSource{})
+		case "passivedns":
+			sources = append(sources, &PassiveDNSSource{})
+		case "bruteforce":
+			if wordlist == "" {
+				return nil, fmt.Errorf("--sources=bruteforce requires --wordlist")
+			}
+			sources = append(sources, &BruteforceSource{WordlistPath: wordlist})
+		case "zonewalk":
+			sources = append(sources, &ZoneWalkSource{})
+		case "":
+			// ignore stray commas
+		default:
+			return nil, fmt.Errorf("unknown source %q", name)
+		}
+	}
+	return sources, nil
+}
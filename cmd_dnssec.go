@@ -0,0 +1,189 @@
+package cli53
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// RunDnssec implements the `cli53 dnssec` subcommand: enable, disable,
+// rotate-zsk, show-ds and export --with-rrsig, all against the zone
+// identified by domain through provider.
+//
+// Against the default Route53 provider, enable/disable/rotate-zsk/show-ds
+// are driven entirely through Route53's native DNSSEC signing API
+// (CreateKeySigningKey/EnableHostedZoneDNSSEC/GetDNSSEC): Route53 manages
+// the ZSK and RRSIG publication itself once signing is enabled, and rejects
+// any attempt to push those record types directly. Other providers fall
+// back to Signer/KeyStore's self-signing path, which is the only option
+// for backends like BindFileProvider that have no native DNSSEC of their
+// own but do accept arbitrary record types.
+func RunDnssec(args []string, provider Provider, store KeyStore, out io.Writer) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: cli53 dnssec <enable|disable|rotate-zsk|show-ds|export> <domain> [flags]")
+	}
+	action, domain := args[0], args[1]
+	fs := flag.NewFlagSet("dnssec "+action, flag.ContinueOnError)
+	rsaFlag := fs.Bool("rsa", false, "use RSASHA256 instead of the default ECDSAP256SHA256")
+	withRRSIG := fs.Bool("with-rrsig", false, "include RRSIG records in export output")
+	kmsKeyArn := fs.String("kms-key-arn", "", "KMS CMK backing the key-signing key (also read from CLI53_DNSSEC_KMS_KEY_ARN)")
+	if err := fs.Parse(args[2:]); err != nil {
+		return err
+	}
+	if *kmsKeyArn == "" {
+		*kmsKeyArn = os.Getenv("CLI53_DNSSEC_KMS_KEY_ARN")
+	}
+
+	alg := ECDSAP256SHA256
+	if *rsaFlag {
+		alg = RSASHA256
+	}
+
+	zoneID, err := zoneIDForDomain(provider, domain)
+	if err != nil {
+		return err
+	}
+
+	if r53, ok := provider.(*Route53Provider); ok {
+		return runRoute53Dnssec(r53.DNSSECManager(), zoneID, action, *kmsKeyArn, out)
+	}
+	return runSelfSignedDnssec(provider, store, zoneID, domain, action, alg, *withRRSIG, out)
+}
+
+func runRoute53Dnssec(manager *Route53DNSSECManager, zoneID, action, kmsKeyArn string, out io.Writer) error {
+	switch action {
+	case "enable":
+		if kmsKeyArn == "" {
+			return fmt.Errorf("dnssec enable: --kms-key-arn (or CLI53_DNSSEC_KMS_KEY_ARN) is required to create a key-signing key")
+		}
+		ds, err := manager.Enable(zoneID, kmsKeyArn)
+		if err != nil {
+			return err
+		}
+		return printDS(out, ds)
+
+	case "disable":
+		return manager.Disable(zoneID)
+
+	case "rotate-zsk":
+		if kmsKeyArn == "" {
+			return fmt.Errorf("dnssec rotate-zsk: --kms-key-arn (or CLI53_DNSSEC_KMS_KEY_ARN) is required to create the replacement key-signing key")
+		}
+		ds, err := manager.RotateKSK(zoneID, kmsKeyArn)
+		if err != nil {
+			return err
+		}
+		return printDS(out, ds)
+
+	case "show-ds":
+		ds, err := manager.ShowDS(zoneID)
+		if err != nil {
+			return err
+		}
+		return printDS(out, ds)
+
+	case "export":
+		return fmt.Errorf("dnssec export: not supported against the route53 provider; Route53 publishes DNSKEY/RRSIG internally and never returns them through ListRecords")
+
+	default:
+		return fmt.Errorf("unknown dnssec action %q", action)
+	}
+}
+
+func runSelfSignedDnssec(provider Provider, store KeyStore, zoneID, domain, action string, alg Algorithm, withRRSIG bool, out io.Writer) error {
+	signer := NewSigner(domain, store)
+
+	switch action {
+	case "enable":
+		current, err := provider.ListRecords(zoneID)
+		if err != nil {
+			return err
+		}
+		changes, ds, err := signer.Enable(alg, current)
+		if err != nil {
+			return err
+		}
+		if err := provider.ApplyChanges(zoneID, changes); err != nil {
+			return err
+		}
+		return printDS(out, ds)
+
+	case "disable":
+		current, err := provider.ListRecords(zoneID)
+		if err != nil {
+			return err
+		}
+		changes, err := signer.Disable(current)
+		if err != nil {
+			return err
+		}
+		return provider.ApplyChanges(zoneID, changes)
+
+	case "rotate-zsk":
+		if err := signer.RotateZSK(alg); err != nil {
+			return err
+		}
+		current, err := provider.ListRecords(zoneID)
+		if err != nil {
+			return err
+		}
+		changes, ds, err := signer.Enable(alg, current)
+		if err != nil {
+			return err
+		}
+		if err := provider.ApplyChanges(zoneID, changes); err != nil {
+			return err
+		}
+		return printDS(out, ds)
+
+	case "show-ds":
+		ds, err := signer.ShowDS()
+		if err != nil {
+			return err
+		}
+		return printDS(out, ds)
+
+	case "export":
+		current, err := provider.ListRecords(zoneID)
+		if err != nil {
+			return err
+		}
+		for _, rrset := range current {
+			if !withRRSIG && *rrset.Type == "RRSIG" {
+				continue
+			}
+			for _, rr := range ConvertRRSetToBind(rrset) {
+				fmt.Fprintln(out, rr.String())
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown dnssec action %q", action)
+	}
+}
+
+func printDS(out io.Writer, records []*dns.DS) error {
+	for _, rec := range records {
+		fmt.Fprintln(out, strings.Replace(rec.String(), "\t", " ", -1))
+	}
+	return nil
+}
+
+// zoneIDForDomain looks up a zone's ID by name through provider.
+func zoneIDForDomain(provider Provider, domain string) (string, error) {
+	zones, err := provider.ListZones()
+	if err != nil {
+		return "", err
+	}
+	for _, z := range zones {
+		if z.Name == domain+"." || z.Name == domain {
+			return z.ID, nil
+		}
+	}
+	return "", fmt.Errorf("no such domain: %s", domain)
+}
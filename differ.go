@@ -0,0 +1,199 @@
+package cli53
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// rrsetKey uniquely identifies a resource record set the way Route53 does:
+// by name, type and (for weighted/latency/failover sets) set identifier.
+type rrsetKey struct {
+	Name          string
+	Type          string
+	SetIdentifier string
+}
+
+func keyFor(rrset *route53.ResourceRecordSet) rrsetKey {
+	k := rrsetKey{
+		Name: strings.ToLower(aws.StringValue(rrset.Name)),
+		Type: aws.StringValue(rrset.Type),
+	}
+	if rrset.SetIdentifier != nil {
+		k.SetIdentifier = *rrset.SetIdentifier
+	}
+	return k
+}
+
+// apexManaged is the set of record types Differ ignores at the zone apex
+// unless IncludeApex is set, since Route53 manages them itself.
+var apexManaged = map[string]bool{
+	"NS":  true,
+	"SOA": true,
+}
+
+// Differ computes the minimal set of route53.Change actions required to
+// converge a hosted zone's live record sets to a desired state, in the
+// spirit of how dnscontrol reconciles DNS providers.
+type Differ struct {
+	// Origin is the zone's apex name (with trailing dot), used to decide
+	// which record sets are "at the apex" for IncludeApex.
+	Origin string
+
+	// IncludeApex, when true, allows NS/SOA record sets at the apex to be
+	// created, updated or deleted. By default they are left untouched.
+	IncludeApex bool
+
+	// NoPurge, when true, suppresses deletes: record sets present live but
+	// absent from desired are left alone instead of being removed.
+	NoPurge bool
+
+	// Types, if non-empty, restricts the diff to only these record types.
+	Types []string
+}
+
+func (d *Differ) typeAllowed(t string) bool {
+	if len(d.Types) == 0 {
+		return true
+	}
+	for _, want := range d.Types {
+		if strings.EqualFold(want, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Differ) skipApex(rrset *route53.ResourceRecordSet) bool {
+	if d.IncludeApex {
+		return false
+	}
+	return apexManaged[aws.StringValue(rrset.Type)] && strings.EqualFold(aws.StringValue(rrset.Name), d.Origin)
+}
+
+// Diff compares desired against actual and returns the creates, updates and
+// deletes needed to converge actual to desired. Creates use action CREATE
+// and updates use UPSERT; they're returned as separate slices purely for
+// reporting (DiffText's +/~ prefixes) even though Batch merges them back
+// into one ChangeBatch before submission.
+func (d *Differ) Diff(desired, actual []*route53.ResourceRecordSet) (creates, updates, deletes []*route53.Change) {
+	desiredByKey := map[rrsetKey]*route53.ResourceRecordSet{}
+	for _, rrset := range desired {
+		if !d.typeAllowed(aws.StringValue(rrset.Type)) || d.skipApex(rrset) {
+			continue
+		}
+		desiredByKey[keyFor(rrset)] = rrset
+	}
+
+	actualByKey := map[rrsetKey]*route53.ResourceRecordSet{}
+	for _, rrset := range actual {
+		if !d.typeAllowed(aws.StringValue(rrset.Type)) || d.skipApex(rrset) {
+			continue
+		}
+		actualByKey[keyFor(rrset)] = rrset
+	}
+
+	for key, want := range desiredByKey {
+		have, exists := actualByKey[key]
+		if !exists {
+			creates = append(creates, &route53.Change{
+				Action:            aws.String("CREATE"),
+				ResourceRecordSet: want,
+			})
+			continue
+		}
+		if !rrsetsEqual(want, have) {
+			updates = append(updates, &route53.Change{
+				Action:            aws.String("UPSERT"),
+				ResourceRecordSet: want,
+			})
+		}
+	}
+
+	if !d.NoPurge {
+		for key, have := range actualByKey {
+			if _, exists := desiredByKey[key]; !exists {
+				deletes = append(deletes, &route53.Change{
+					Action:            aws.String("DELETE"),
+					ResourceRecordSet: have,
+				})
+			}
+		}
+	}
+
+	sortChanges(creates)
+	sortChanges(updates)
+	sortChanges(deletes)
+
+	return creates, updates, deletes
+}
+
+func sortChanges(changes []*route53.Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i].ResourceRecordSet, changes[j].ResourceRecordSet
+		if aws.StringValue(a.Name) != aws.StringValue(b.Name) {
+			return aws.StringValue(a.Name) < aws.StringValue(b.Name)
+		}
+		return aws.StringValue(a.Type) < aws.StringValue(b.Type)
+	})
+}
+
+func rrsetsEqual(a, b *route53.ResourceRecordSet) bool {
+	if aws.Int64Value(a.TTL) != aws.Int64Value(b.TTL) {
+		return false
+	}
+	if len(a.ResourceRecords) != len(b.ResourceRecords) {
+		return false
+	}
+	// Compare as multisets, not sets: a=[x,y] and b=[x,x] both satisfy a
+	// plain subset check but are not the same RRset.
+	acounts := map[string]int{}
+	for _, rr := range a.ResourceRecords {
+		acounts[aws.StringValue(rr.Value)]++
+	}
+	for _, rr := range b.ResourceRecords {
+		value := aws.StringValue(rr.Value)
+		if acounts[value] == 0 {
+			return false
+		}
+		acounts[value]--
+	}
+	return true
+}
+
+// Batch combines creates, updates and deletes into a single ChangeBatch,
+// whose Changes RunApply submits through Provider.ApplyChanges (for
+// Route53, ChangeExecutor may split them across several requests).
+func Batch(creates, updates, deletes []*route53.Change) *route53.ChangeBatch {
+	changes := make([]*route53.Change, 0, len(creates)+len(updates)+len(deletes))
+	changes = append(changes, creates...)
+	changes = append(changes, updates...)
+	changes = append(changes, deletes...)
+	return &route53.ChangeBatch{Changes: changes}
+}
+
+// DiffText renders creates/updates/deletes as a unified-style textual diff of
+// BIND lines, reusing ConvertRRSetToBind so --dry-run output matches the
+// zone file format the rest of cli53 already produces.
+func DiffText(creates, updates, deletes []*route53.Change) string {
+	var b strings.Builder
+	for _, c := range deletes {
+		for _, rr := range ConvertRRSetToBind(c.ResourceRecordSet) {
+			fmt.Fprintf(&b, "- %s\n", rr.String())
+		}
+	}
+	for _, c := range creates {
+		for _, rr := range ConvertRRSetToBind(c.ResourceRecordSet) {
+			fmt.Fprintf(&b, "+ %s\n", rr.String())
+		}
+	}
+	for _, c := range updates {
+		for _, rr := range ConvertRRSetToBind(c.ResourceRecordSet) {
+			fmt.Fprintf(&b, "~ %s\n", rr.String())
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,149 @@
+package cli53
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Candidate is a single discovered hostname, before resolution.
+type Candidate struct {
+	Name string
+}
+
+// Source is a pluggable subdomain discovery technique. Each implementation
+// is blind to the others; Engine aggregates and de-duplicates across all of
+// them.
+type Source interface {
+	// Name identifies the source for --sources selection and reporting.
+	Name() string
+
+	// Discover returns candidate subdomains of domain. Sources that need a
+	// wordlist (e.g. Bruteforce) are configured at construction time.
+	Discover(domain string) ([]Candidate, error)
+}
+
+// Resolver resolves a hostname to the record values that belong in a BIND
+// zone file (A/AAAA/CNAME). It exists as an interface so tests can avoid
+// real DNS lookups.
+type Resolver interface {
+	Resolve(name string) (recordType string, values []string, err error)
+}
+
+// Engine runs a set of Sources one after another, resolves every unique
+// candidate they produce with a bounded number of workers, and aggregates
+// the results into a BIND-importable record set.
+type Engine struct {
+	Sources     []Source
+	Resolver    Resolver
+	Concurrency int           // number of concurrent resolutions; 0 means 10
+	RateLimit   time.Duration // minimum delay between resolutions per worker
+}
+
+// DiscoverResult is one resolved record, ready to render as BIND.
+type DiscoverResult struct {
+	Name  string
+	Type  string
+	Value string
+}
+
+// Run executes every configured Source against domain, resolves the
+// de-duplicated union of candidates, and returns the discovered records
+// sorted by name.
+func (e *Engine) Run(domain string) ([]DiscoverResult, error) {
+	concurrency := e.Concurrency
+	if concurrency <= 0 {
+		concurrency = 10
+	}
+
+	seen := map[string]bool{}
+	var names []string
+	for _, source := range e.Sources {
+		candidates, err := source.Discover(domain)
+		if err != nil {
+			continue // a failing source (e.g. crt.sh unreachable) shouldn't abort discovery
+		}
+		for _, c := range candidates {
+			name := strings.ToLower(strings.TrimSuffix(c.Name, "."))
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	work := make(chan string)
+	results := make(chan DiscoverResult)
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range work {
+				if e.RateLimit > 0 {
+					time.Sleep(e.RateLimit)
+				}
+				typ, values, err := e.Resolver.Resolve(name)
+				if err != nil {
+					continue
+				}
+				for _, v := range values {
+					results <- DiscoverResult{Name: name, Type: typ, Value: v}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		for _, name := range names {
+			work <- name
+		}
+		close(work)
+		wg.Wait()
+		close(results)
+	}()
+
+	var out []DiscoverResult
+	for r := range results {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Type < out[j].Type
+	})
+	return out, nil
+}
+
+// ToRecordSets converts discovered results into route53.ResourceRecordSet,
+// grouping same (name, type) values into one multi-value RRset the same way
+// the rest of cli53 does, so Engine output can feed straight into Differ.
+func ToRecordSets(results []DiscoverResult) []*route53.ResourceRecordSet {
+	grouped := map[rrsetKey]*route53.ResourceRecordSet{}
+	var order []rrsetKey
+	for _, r := range results {
+		key := rrsetKey{Name: r.Name, Type: r.Type}
+		rrset, exists := grouped[key]
+		if !exists {
+			rrset = &route53.ResourceRecordSet{
+				Name: aws.String(r.Name),
+				Type: aws.String(r.Type),
+				TTL:  aws.Int64(300),
+			}
+			grouped[key] = rrset
+			order = append(order, key)
+		}
+		rrset.ResourceRecords = append(rrset.ResourceRecords, &route53.ResourceRecord{Value: aws.String(r.Value)})
+	}
+	rrsets := make([]*route53.ResourceRecordSet, 0, len(order))
+	for _, key := range order {
+		rrsets = append(rrsets, grouped[key])
+	}
+	return rrsets
+}
@@ -0,0 +1,35 @@
+package cli53
+
+import "net"
+
+// LookupResolver resolves names using the system resolver: a name that is
+// an alias resolves to its CNAME target; otherwise it resolves to its A
+// records, falling back to AAAA when it has no IPv4 addresses.
+type LookupResolver struct{}
+
+func (LookupResolver) Resolve(name string) (string, []string, error) {
+	if cname, err := net.LookupCNAME(name); err == nil && cname != "" && cname != name+"." {
+		return "CNAME", []string{cname}, nil
+	}
+
+	ips, err := net.LookupIP(name)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var v4, v6 []string
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			v4 = append(v4, ip4.String())
+		} else {
+			v6 = append(v6, ip.String())
+		}
+	}
+	if len(v4) > 0 {
+		return "A", v4, nil
+	}
+	if len(v6) > 0 {
+		return "AAAA", v6, nil
+	}
+	return "", nil, nil
+}
@@ -0,0 +1,52 @@
+package cli53
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// fixtureEntry is one name's canned resolution in a resolver fixture file.
+type fixtureEntry struct {
+	Type   string   `json:"type"`
+	Values []string `json:"values"`
+}
+
+// FixtureResolver resolves names from a fixed, in-memory table instead of
+// live DNS. It exists so discover scenarios are hermetic and deterministic:
+// a freshly created test zone's subdomains are never actually delegated to
+// Route53's nameservers, so a real resolver can never observe them.
+type FixtureResolver map[string]fixtureEntry
+
+// LoadFixtureResolver reads a JSON file of the form
+// {"name": {"type": "A", "values": ["1.2.3.4"]}, ...}.
+func LoadFixtureResolver(path string) (FixtureResolver, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var resolver FixtureResolver
+	if err := json.NewDecoder(f).Decode(&resolver); err != nil {
+		return nil, fmt.Errorf("parsing resolver fixture %s: %s", path, err)
+	}
+	return resolver, nil
+}
+
+// Resolve looks up name exactly first, then falls back to treating a
+// fixture key as a leading label: an entry keyed "www" resolves any name
+// of the form "www.<anything>", which lets a fixture stay valid across the
+// randomized per-scenario test domain.
+func (r FixtureResolver) Resolve(name string) (string, []string, error) {
+	if entry, ok := r[name]; ok {
+		return entry.Type, entry.Values, nil
+	}
+	for key, entry := range r {
+		if strings.HasPrefix(name, key+".") {
+			return entry.Type, entry.Values, nil
+		}
+	}
+	return "", nil, fmt.Errorf("fixture resolver: no entry for %s", name)
+}
@@ -0,0 +1,129 @@
+package cli53
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// CTSource discovers subdomains by scraping certificate transparency logs
+// via crt.sh's JSON API.
+type CTSource struct {
+	// Endpoint overrides the default crt.sh URL; used by tests.
+	Endpoint string
+}
+
+func (s *CTSource) Name() string { return "ct" }
+
+func (s *CTSource) Discover(domain string) ([]Candidate, error) {
+	endpoint := s.Endpoint
+	if endpoint == "" {
+		endpoint = "https://crt.sh/?q=%25." + domain + "&output=json"
+	}
+	resp, err := http.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("ct: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var entries []struct {
+		NameValue string `json:"name_value"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("ct: decoding crt.sh response: %s", err)
+	}
+
+	var candidates []Candidate
+	for _, entry := range entries {
+		for _, name := range strings.Split(entry.NameValue, "\n") {
+			name = strings.TrimSpace(name)
+			if name != "" && !strings.Contains(name, "*") {
+				candidates = append(candidates, Candidate{Name: name})
+			}
+		}
+	}
+	return candidates, nil
+}
+
+// PassiveDNSSource discovers subdomains via a passive-DNS API, the way
+// SecurityTrails/VirusTotal-style services expose historical resolutions.
+type PassiveDNSSource struct {
+	Endpoint string // e.g. "https://api.example.com/v1/subdomains/%s"
+	APIKey   string
+}
+
+func (s *PassiveDNSSource) Name() string { return "passivedns" }
+
+func (s *PassiveDNSSource) Discover(domain string) ([]Candidate, error) {
+	if s.Endpoint == "" {
+		return nil, fmt.Errorf("passivedns: no endpoint configured")
+	}
+	req, err := http.NewRequest("GET", fmt.Sprintf(s.Endpoint, domain), nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("passivedns: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var names []string
+	if err := json.NewDecoder(resp.Body).Decode(&names); err != nil {
+		return nil, fmt.Errorf("passivedns: decoding response: %s", err)
+	}
+
+	candidates := make([]Candidate, 0, len(names))
+	for _, name := range names {
+		candidates = append(candidates, Candidate{Name: name})
+	}
+	return candidates, nil
+}
+
+// BruteforceSource discovers subdomains by prepending each line of a
+// wordlist to the domain. Resolution (and thus confirming which candidates
+// are real) happens later in Engine.Run.
+type BruteforceSource struct {
+	WordlistPath string
+}
+
+func (s *BruteforceSource) Name() string { return "bruteforce" }
+
+func (s *BruteforceSource) Discover(domain string) ([]Candidate, error) {
+	f, err := os.Open(s.WordlistPath)
+	if err != nil {
+		return nil, fmt.Errorf("bruteforce: %s", err)
+	}
+	defer f.Close()
+
+	var candidates []Candidate
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		candidates = append(candidates, Candidate{Name: word + "." + domain})
+	}
+	return candidates, scanner.Err()
+}
+
+// ZoneWalkSource is the "zonewalk" source, for discovering subdomains by
+// walking a NSEC chain. Its Discover performs no NSEC queries: it always
+// returns an error and no candidates, so Engine.Run skips it.
+type ZoneWalkSource struct {
+	// Nameserver is the authoritative server to query, "ns1.example.com:53".
+	Nameserver string
+}
+
+func (s *ZoneWalkSource) Name() string { return "zonewalk" }
+
+func (s *ZoneWalkSource) Discover(domain string) ([]Candidate, error) {
+	return nil, fmt.Errorf("zonewalk: not yet implemented (requires an NSEC-walking DNS client)")
+}
@@ -0,0 +1,369 @@
+package cli53
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/miekg/dns"
+)
+
+// Algorithm identifies a DNSSEC signing algorithm cli53 can generate keys
+// for. ECDSAP256SHA256 is the default: shorter keys and signatures than
+// RSA at an equivalent security level.
+type Algorithm int
+
+const (
+	ECDSAP256SHA256 Algorithm = iota
+	RSASHA256
+)
+
+func (a Algorithm) dnsAlgorithm() uint8 {
+	switch a {
+	case RSASHA256:
+		return dns.RSASHA256
+	default:
+		return dns.ECDSAP256SHA256
+	}
+}
+
+// keyBits returns the key size to generate for a, per RFC 8624's minimum
+// recommendations: 256 bits for the P-256 curve ECDSA uses, 2048 bits for
+// RSA (256 would be a trivially-factorable RSA modulus).
+func (a Algorithm) keyBits() int {
+	switch a {
+	case RSASHA256:
+		return 2048
+	default:
+		return 256
+	}
+}
+
+// KeyRole distinguishes a key-signing key (signs the DNSKEY RRset and is
+// referenced by the parent's DS record) from a zone-signing key (signs
+// everything else).
+type KeyRole int
+
+const (
+	KSK KeyRole = iota
+	ZSK
+)
+
+// KeyStore persists DNSSEC private key material. Implementations must keep
+// the private key out of Route53 entirely; only the derived DNSKEY/DS/RRSIG
+// records are ever published.
+type KeyStore interface {
+	// Save stores the key pair for a zone/role, overwriting any existing one.
+	Save(zone string, role KeyRole, key *dns.DNSKEY, priv dns.PrivateKey) error
+	// Load retrieves a previously saved key pair.
+	Load(zone string, role KeyRole) (*dns.DNSKEY, dns.PrivateKey, error)
+	// Delete removes a key pair, e.g. after rotate-zsk retires the old key.
+	Delete(zone string, role KeyRole) error
+}
+
+// SigningThreshold is the default age at which a Signer considers an
+// RRSIG's inception stale enough to need re-signing.
+const SigningThreshold = 72 * time.Hour
+
+// signatureValidity is how long a freshly produced RRSIG is valid for.
+const signatureValidity = 30 * 24 * time.Hour
+
+// Signer manages DNSSEC for a single hosted zone: generating keys, signing
+// RRsets, publishing DNSKEY/DS records and keeping RRSIGs fresh.
+type Signer struct {
+	Zone      string
+	Store     KeyStore
+	Threshold time.Duration
+}
+
+// NewSigner returns a Signer using SigningThreshold as its default re-sign
+// threshold.
+func NewSigner(zone string, store KeyStore) *Signer {
+	return &Signer{Zone: zone, Store: store, Threshold: SigningThreshold}
+}
+
+// Enable generates a KSK and ZSK for the zone (if they don't already exist),
+// signs the DNSKEY RRset and every RRset in current, and returns both the
+// route53.Change batch needed to publish DNSKEY/RRSIG records and the DS
+// records that must be published with the domain's registrar/parent zone.
+func (s *Signer) Enable(alg Algorithm, current []*route53.ResourceRecordSet) ([]*route53.Change, []*dns.DS, error) {
+	if _, _, err := s.Store.Load(s.Zone, KSK); err != nil {
+		if err := s.generateKey(alg, KSK); err != nil {
+			return nil, nil, fmt.Errorf("generating KSK: %s", err)
+		}
+	}
+	if _, _, err := s.Store.Load(s.Zone, ZSK); err != nil {
+		if err := s.generateKey(alg, ZSK); err != nil {
+			return nil, nil, fmt.Errorf("generating ZSK: %s", err)
+		}
+	}
+
+	dnskeyChanges, err := s.signDNSKEY()
+	if err != nil {
+		return nil, nil, fmt.Errorf("signing DNSKEY RRset: %s", err)
+	}
+
+	rrsetChanges, err := s.SignZone(current)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ds, err := s.ShowDS()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := append(dnskeyChanges, rrsetChanges...)
+	return changes, ds, nil
+}
+
+// Disable removes both key pairs, stopping signing for the zone, and
+// returns the route53.Change deletes needed to strip the DNSKEY/RRSIG
+// records it previously published out of current.
+func (s *Signer) Disable(current []*route53.ResourceRecordSet) ([]*route53.Change, error) {
+	var changes []*route53.Change
+	for _, rrset := range current {
+		if aws.StringValue(rrset.Type) == "DNSKEY" || aws.StringValue(rrset.Type) == "RRSIG" {
+			changes = append(changes, &route53.Change{
+				Action:            aws.String("DELETE"),
+				ResourceRecordSet: rrset,
+			})
+		}
+	}
+
+	if err := s.Store.Delete(s.Zone, KSK); err != nil {
+		return changes, err
+	}
+	if err := s.Store.Delete(s.Zone, ZSK); err != nil {
+		return changes, err
+	}
+	return changes, nil
+}
+
+// RotateZSK generates a fresh ZSK, retiring the previous one. The KSK is
+// left untouched since rotating it would require republishing a new DS
+// record with the parent zone. The caller still needs to re-sign the zone
+// (SignZone) and republish the DNSKEY RRset (signDNSKEY via Enable) once
+// the new ZSK is in place.
+func (s *Signer) RotateZSK(alg Algorithm) error {
+	if err := s.Store.Delete(s.Zone, ZSK); err != nil {
+		return err
+	}
+	return s.generateKey(alg, ZSK)
+}
+
+// ShowDS returns the DS records derived from the zone's current KSK, one per
+// supported digest type, for publishing at the parent zone.
+func (s *Signer) ShowDS() ([]*dns.DS, error) {
+	ksk, _, err := s.Store.Load(s.Zone, KSK)
+	if err != nil {
+		return nil, fmt.Errorf("no KSK for zone %s: %s", s.Zone, err)
+	}
+	return []*dns.DS{
+		ksk.ToDS(dns.SHA256),
+	}, nil
+}
+
+// Sign produces an RRSIG for rrset using the zone's ZSK (or KSK, for the
+// DNSKEY RRset itself), valid from now until expiry.
+func (s *Signer) Sign(rrset []dns.RR, expiry time.Time) (*dns.RRSIG, error) {
+	role := ZSK
+	if len(rrset) > 0 && rrset[0].Header().Rrtype == dns.TypeDNSKEY {
+		role = KSK
+	}
+	key, priv, err := s.Store.Load(s.Zone, role)
+	if err != nil {
+		return nil, err
+	}
+
+	rrsig := &dns.RRSIG{
+		Hdr: dns.RR_Header{
+			Name:   s.Zone,
+			Rrtype: dns.TypeRRSIG,
+			Class:  dns.ClassINET,
+		},
+		Algorithm:  key.Algorithm,
+		Expiration: uint32(expiry.Unix()),
+		Inception:  uint32(time.Now().Unix()),
+		KeyTag:     key.KeyTag(),
+		SignerName: s.Zone,
+	}
+	if err := rrsig.Sign(priv, rrset); err != nil {
+		return nil, fmt.Errorf("signing %s RRset: %s", dns.TypeToString[rrset[0].Header().Rrtype], err)
+	}
+	return rrsig, nil
+}
+
+// SignZone signs every RRset in current that isn't apex-managed NS/SOA or
+// DNSSEC metadata itself, returning the UPSERT changes needed to publish
+// the resulting RRSIG records. Every covered type at a given owner name
+// (e.g. A and AAAA at "www") is grouped into a single RRSIG RRset, since
+// Route53 keys change batches by (name, type) and would otherwise see two
+// conflicting UPSERTs for the same RRset in one batch.
+func (s *Signer) SignZone(current []*route53.ResourceRecordSet) ([]*route53.Change, error) {
+	expiry := time.Now().Add(signatureValidity)
+
+	type signedName struct {
+		ttl    int64
+		rrsigs []*dns.RRSIG
+	}
+	byName := map[string]*signedName{}
+	var order []string
+
+	for _, rrset := range current {
+		typ := aws.StringValue(rrset.Type)
+		if apexManaged[typ] || typ == "RRSIG" || typ == "DNSKEY" {
+			continue
+		}
+		rrs := ConvertRRSetToBind(rrset)
+		if len(rrs) == 0 {
+			continue
+		}
+		rrsig, err := s.Sign(rrs, expiry)
+		if err != nil {
+			return nil, err
+		}
+
+		name := aws.StringValue(rrset.Name)
+		entry, ok := byName[name]
+		if !ok {
+			entry = &signedName{ttl: aws.Int64Value(rrset.TTL)}
+			byName[name] = entry
+			order = append(order, name)
+		}
+		entry.rrsigs = append(entry.rrsigs, rrsig)
+	}
+
+	changes := make([]*route53.Change, len(order))
+	for i, name := range order {
+		entry := byName[name]
+		changes[i] = &route53.Change{
+			Action:            aws.String("UPSERT"),
+			ResourceRecordSet: rrsigRecordSet(name, entry.ttl, entry.rrsigs...),
+		}
+	}
+	return changes, nil
+}
+
+// Resign finds published RRSIG records in current whose inception is older
+// than the signer's threshold and re-signs the RRset each covers, returning
+// the UPSERT changes needed to republish fresh signatures. This is the
+// signing scheduler's re-sign step; no cli53 subcommand calls it (cli53
+// dnssec enable re-signs every RRset through Enable instead).
+func (s *Signer) Resign(current []*route53.ResourceRecordSet) ([]*route53.Change, error) {
+	byKey := map[rrsetKey]*route53.ResourceRecordSet{}
+	for _, rrset := range current {
+		byKey[keyFor(rrset)] = rrset
+	}
+
+	seen := map[rrsetKey]bool{}
+	var stale []*route53.ResourceRecordSet
+	for _, rrset := range current {
+		if aws.StringValue(rrset.Type) != "RRSIG" {
+			continue
+		}
+		for _, rr := range ConvertRRSetToBind(rrset) {
+			rrsig, ok := rr.(*dns.RRSIG)
+			if !ok || !s.NeedsResign(rrsig) {
+				continue
+			}
+			key := rrsetKey{Name: strings.ToLower(aws.StringValue(rrset.Name)), Type: dns.TypeToString[rrsig.TypeCovered]}
+			if seen[key] {
+				continue
+			}
+			if target, ok := byKey[key]; ok {
+				stale = append(stale, target)
+				seen[key] = true
+			}
+		}
+	}
+	return s.SignZone(stale)
+}
+
+// NeedsResign reports whether rrsig's inception is older than the signer's
+// threshold, meaning a signing scheduler should replace it.
+func (s *Signer) NeedsResign(rrsig *dns.RRSIG) bool {
+	inception := time.Unix(int64(rrsig.Inception), 0)
+	return time.Since(inception) > s.Threshold
+}
+
+func (s *Signer) generateKey(alg Algorithm, role KeyRole) error {
+	flags := uint16(dns.ZONE)
+	if role == KSK {
+		flags |= dns.SEP
+	}
+	dnskey := &dns.DNSKEY{
+		Hdr: dns.RR_Header{
+			Name:   s.Zone,
+			Rrtype: dns.TypeDNSKEY,
+			Class:  dns.ClassINET,
+		},
+		Flags:     flags,
+		Protocol:  3,
+		Algorithm: alg.dnsAlgorithm(),
+	}
+	priv, err := dnskey.Generate(alg.keyBits())
+	if err != nil {
+		return err
+	}
+	return s.Store.Save(s.Zone, role, dnskey, priv)
+}
+
+// signDNSKEY builds the zone's DNSKEY RRset from its KSK and ZSK, signs it
+// with the KSK, and returns the UPSERT changes needed to publish both the
+// DNSKEY RRset and its RRSIG.
+func (s *Signer) signDNSKEY() ([]*route53.Change, error) {
+	ksk, _, err := s.Store.Load(s.Zone, KSK)
+	if err != nil {
+		return nil, err
+	}
+	zsk, _, err := s.Store.Load(s.Zone, ZSK)
+	if err != nil {
+		return nil, err
+	}
+
+	dnskeySet := &route53.ResourceRecordSet{
+		Name: aws.String(s.Zone),
+		Type: aws.String("DNSKEY"),
+		TTL:  aws.Int64(3600),
+		ResourceRecords: []*route53.ResourceRecord{
+			{Value: aws.String(rrValue(ksk))},
+			{Value: aws.String(rrValue(zsk))},
+		},
+	}
+
+	rrsig, err := s.Sign([]dns.RR{ksk, zsk}, time.Now().Add(signatureValidity))
+	if err != nil {
+		return nil, err
+	}
+
+	return []*route53.Change{
+		{Action: aws.String("UPSERT"), ResourceRecordSet: dnskeySet},
+		{Action: aws.String("UPSERT"), ResourceRecordSet: rrsigRecordSet(s.Zone, 3600, rrsig)},
+	}, nil
+}
+
+// rrValue renders rr's value portion only, the way ConvertRRSetToBind
+// stores individual ResourceRecord values (no owner name, TTL or class).
+func rrValue(rr dns.RR) string {
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
+
+// rrsigRecordSet wraps one or more RRSIGs covering the same owner name as
+// the single RRSIG RRset Route53 expects for publishing: Route53 keys
+// record sets by (name, type), so every covered type at a name must be one
+// UPSERT with one ResourceRecord per RRSIG, not a separate change each.
+func rrsigRecordSet(name string, ttl int64, rrsigs ...*dns.RRSIG) *route53.ResourceRecordSet {
+	records := make([]*route53.ResourceRecord, len(rrsigs))
+	for i, rrsig := range rrsigs {
+		records[i] = &route53.ResourceRecord{Value: aws.String(rrValue(rrsig))}
+	}
+	return &route53.ResourceRecordSet{
+		Name:            aws.String(name),
+		Type:            aws.String("RRSIG"),
+		TTL:             aws.Int64(ttl),
+		ResourceRecords: records,
+	}
+}
@@ -0,0 +1,374 @@
+package cli53
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/miekg/dns"
+)
+
+// FileKeyStore stores DNSSEC keys as files underneath Dir, named
+// "<zone>.ksk" or "<zone>.zsk" plus ".key"/".private": the .key file holds
+// the DNSKEY record and the .private file holds the private key in BIND's
+// private-key format, so keys can be inspected or backed up with familiar
+// tooling.
+type FileKeyStore struct {
+	Dir string
+}
+
+func (f *FileKeyStore) path(zone string, role KeyRole, suffix string) string {
+	name := zone
+	if role == KSK {
+		name += ".ksk"
+	} else {
+		name += ".zsk"
+	}
+	return filepath.Join(f.Dir, name+suffix)
+}
+
+// Save writes the DNSKEY record and private key to separate files.
+func (f *FileKeyStore) Save(zone string, role KeyRole, key *dns.DNSKEY, priv dns.PrivateKey) error {
+	if err := os.MkdirAll(f.Dir, 0700); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(f.path(zone, role, ".key"), []byte(key.String()+"\n"), 0600); err != nil {
+		return err
+	}
+	privBytes := []byte(key.PrivateKeyString(priv))
+	return ioutil.WriteFile(f.path(zone, role, ".private"), privBytes, 0600)
+}
+
+// Load reads back a previously saved key pair.
+func (f *FileKeyStore) Load(zone string, role KeyRole) (*dns.DNSKEY, dns.PrivateKey, error) {
+	keyBytes, err := ioutil.ReadFile(f.path(zone, role, ".key"))
+	if err != nil {
+		return nil, nil, err
+	}
+	rr, err := dns.NewRR(string(keyBytes))
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing %s: %s", f.path(zone, role, ".key"), err)
+	}
+	dnskey, ok := rr.(*dns.DNSKEY)
+	if !ok {
+		return nil, nil, fmt.Errorf("%s does not contain a DNSKEY record", f.path(zone, role, ".key"))
+	}
+
+	privBytes, err := ioutil.ReadFile(f.path(zone, role, ".private"))
+	if err != nil {
+		return nil, nil, err
+	}
+	priv, err := dnskey.ReadPrivateKey(bytes.NewReader(privBytes), f.path(zone, role, ".private"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing %s: %s", f.path(zone, role, ".private"), err)
+	}
+	return dnskey, priv, nil
+}
+
+// Delete removes both files for a key pair.
+func (f *FileKeyStore) Delete(zone string, role KeyRole) error {
+	if err := os.Remove(f.path(zone, role, ".key")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(f.path(zone, role, ".private")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// KMSKeyStore is a KeyStore backed by AWS KMS asymmetric keys: each
+// zone/role pair gets its own KMS customer master key, generated and held
+// entirely inside KMS, so the private key material never leaves KMS. Save
+// only ever sees the accompanying local key pair Signer.generateKey builds
+// (the KeyStore interface requires one) long enough to pick a matching KMS
+// KeySpec; that local private key is discarded immediately afterwards and
+// never written to disk, so the only thing persisted locally is the
+// zone/role -> KMS KeyID mapping.
+//
+// The dns.PrivateKey Load returns is a kmsPrivateKey: it implements
+// crypto.Signer itself, so dns.RRSIG.Sign drives the actual signing the
+// same way it would for a FileKeyStore key, except every signature is
+// produced by a kms.Sign call instead of local math. See kmsPrivateKey.Sign
+// for the ECDSA DER-to-raw conversion this requires.
+type KMSKeyStore struct {
+	Client *kms.KMS
+
+	// Dir is where the zone/role -> KMS KeyID mapping is persisted. No
+	// private key material is ever stored here.
+	Dir string
+}
+
+// NewKMSKeyStore wraps an existing kms.KMS client.
+func NewKMSKeyStore(client *kms.KMS, dir string) *KMSKeyStore {
+	return &KMSKeyStore{Client: client, Dir: dir}
+}
+
+func (k *KMSKeyStore) mappingPath() string {
+	return filepath.Join(k.Dir, "kms-keys.json")
+}
+
+func (k *KMSKeyStore) loadMapping() (map[string]string, error) {
+	mapping := map[string]string{}
+	b, err := ioutil.ReadFile(k.mappingPath())
+	if os.IsNotExist(err) {
+		return mapping, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &mapping); err != nil {
+		return nil, fmt.Errorf("parsing %s: %s", k.mappingPath(), err)
+	}
+	return mapping, nil
+}
+
+func (k *KMSKeyStore) saveMapping(mapping map[string]string) error {
+	if err := os.MkdirAll(k.Dir, 0700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(mapping, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(k.mappingPath(), b, 0600)
+}
+
+func mappingKey(zone string, role KeyRole) string {
+	if role == KSK {
+		return zone + ".ksk"
+	}
+	return zone + ".zsk"
+}
+
+func roleName(role KeyRole) string {
+	if role == KSK {
+		return "KSK"
+	}
+	return "ZSK"
+}
+
+func keySpecFor(alg uint8) (string, error) {
+	switch alg {
+	case dns.RSASHA256:
+		return kms.CustomerMasterKeySpecRsa2048, nil
+	case dns.ECDSAP256SHA256:
+		return kms.CustomerMasterKeySpecEccNistP256, nil
+	default:
+		return "", fmt.Errorf("KMSKeyStore: unsupported algorithm %d", alg)
+	}
+}
+
+// Save creates a new KMS asymmetric signing key matching key's algorithm
+// and records its KeyID; priv exists only because Signer.generateKey always
+// builds a key pair locally before handing it to KeyStore.Save, and is
+// never used or persisted here.
+func (k *KMSKeyStore) Save(zone string, role KeyRole, key *dns.DNSKEY, priv dns.PrivateKey) error {
+	spec, err := keySpecFor(key.Algorithm)
+	if err != nil {
+		return err
+	}
+	out, err := k.Client.CreateKey(&kms.CreateKeyInput{
+		KeyUsage:              aws.String(kms.KeyUsageTypeSignVerify),
+		CustomerMasterKeySpec: aws.String(spec),
+		Description:           aws.String(fmt.Sprintf("cli53 DNSSEC %s key for %s", roleName(role), zone)),
+	})
+	if err != nil {
+		return fmt.Errorf("KMSKeyStore: creating key: %s", err)
+	}
+
+	mapping, err := k.loadMapping()
+	if err != nil {
+		return err
+	}
+	mapping[mappingKey(zone, role)] = aws.StringValue(out.KeyMetadata.KeyId)
+	return k.saveMapping(mapping)
+}
+
+// Load fetches the public half of a previously created KMS key and rebuilds
+// the DNSKEY record from it. The returned dns.PrivateKey is a kmsPrivateKey
+// handle onto the KMS key, not local key material; dns.RRSIG.Sign signs
+// with it through kms.Sign (see kmsPrivateKey.Sign).
+func (k *KMSKeyStore) Load(zone string, role KeyRole) (*dns.DNSKEY, dns.PrivateKey, error) {
+	mapping, err := k.loadMapping()
+	if err != nil {
+		return nil, nil, err
+	}
+	keyID, ok := mapping[mappingKey(zone, role)]
+	if !ok {
+		return nil, nil, fmt.Errorf("KMSKeyStore: no key recorded for %s %s", zone, roleName(role))
+	}
+
+	out, err := k.Client.GetPublicKey(&kms.GetPublicKeyInput{KeyId: aws.String(keyID)})
+	if err != nil {
+		return nil, nil, fmt.Errorf("KMSKeyStore: fetching public key: %s", err)
+	}
+	pub, err := x509.ParsePKIXPublicKey(out.PublicKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("KMSKeyStore: parsing public key: %s", err)
+	}
+
+	flags := uint16(dns.ZONE)
+	if role == KSK {
+		flags |= dns.SEP
+	}
+	dnskey := &dns.DNSKEY{
+		Hdr:      dns.RR_Header{Name: zone, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET},
+		Flags:    flags,
+		Protocol: 3,
+	}
+
+	switch p := pub.(type) {
+	case *ecdsa.PublicKey:
+		dnskey.Algorithm = dns.ECDSAP256SHA256
+		dnskey.PublicKey = ecdsaPublicKeyToDNSKEY(p)
+	case *rsa.PublicKey:
+		dnskey.Algorithm = dns.RSASHA256
+		dnskey.PublicKey = rsaPublicKeyToDNSKEY(p)
+	default:
+		return nil, nil, fmt.Errorf("KMSKeyStore: unsupported KMS public key type %T", pub)
+	}
+
+	return dnskey, &kmsPrivateKey{client: k.Client, keyID: keyID, public: pub}, nil
+}
+
+// Delete schedules the KMS key for deletion (KMS never deletes a CMK
+// immediately, to guard against accidental loss of access to signed data)
+// and forgets its local mapping entry.
+func (k *KMSKeyStore) Delete(zone string, role KeyRole) error {
+	mapping, err := k.loadMapping()
+	if err != nil {
+		return err
+	}
+	key := mappingKey(zone, role)
+	keyID, ok := mapping[key]
+	if !ok {
+		return nil
+	}
+	if _, err := k.Client.ScheduleKeyDeletion(&kms.ScheduleKeyDeletionInput{
+		KeyId:               aws.String(keyID),
+		PendingWindowInDays: aws.Int64(7),
+	}); err != nil {
+		return fmt.Errorf("KMSKeyStore: scheduling key deletion: %s", err)
+	}
+	delete(mapping, key)
+	return k.saveMapping(mapping)
+}
+
+// padLeft pads b with leading zero bytes until it is size long, the way
+// DNSSEC's fixed-width ECDSA point encoding requires.
+func padLeft(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
+
+// ecdsaPublicKeyToDNSKEY encodes pub the way RFC 6605 requires: the
+// concatenation of the curve point's X and Y coordinates, each padded to
+// the curve's field size.
+func ecdsaPublicKeyToDNSKEY(pub *ecdsa.PublicKey) string {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	buf := append(padLeft(pub.X.Bytes(), size), padLeft(pub.Y.Bytes(), size)...)
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// rsaPublicKeyToDNSKEY encodes pub the way RFC 3110 requires: an exponent
+// length prefix, the exponent, then the modulus.
+func rsaPublicKeyToDNSKEY(pub *rsa.PublicKey) string {
+	e := big.NewInt(int64(pub.E)).Bytes()
+	n := pub.N.Bytes()
+
+	var buf []byte
+	if len(e) < 256 {
+		buf = append(buf, byte(len(e)))
+	} else {
+		buf = append(buf, 0, byte(len(e)>>8), byte(len(e)))
+	}
+	buf = append(buf, e...)
+	buf = append(buf, n...)
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// kmsPrivateKey is the dns.PrivateKey handle Load returns for a KMSKeyStore
+// key: a crypto.Signer that turns every Sign call into a kms.Sign request,
+// so the private key itself never exists outside KMS.
+type kmsPrivateKey struct {
+	client *kms.KMS
+	keyID  string
+	public crypto.PublicKey
+}
+
+func (k *kmsPrivateKey) Public() crypto.PublicKey { return k.public }
+
+func (k *kmsPrivateKey) Equal(x crypto.PrivateKey) bool {
+	other, ok := x.(*kmsPrivateKey)
+	return ok && other.keyID == k.keyID
+}
+
+// asn1EcdsaSignature is the ASN.1 sequence KMS returns for an ECDSA
+// signature: two arbitrary-precision integers, r and s.
+type asn1EcdsaSignature struct {
+	R, S *big.Int
+}
+
+// Sign signs digest (already hashed by the caller per opts) via KMS and
+// returns the signature in the format DNSSEC's wire encoding expects: KMS's
+// RSASSA_PKCS1_V1_5_SHA_256 output is already the raw PKCS#1 v1.5 signature
+// RFC 3110 wants, but its ECDSA_SHA_256 output is an ASN.1 DER-encoded
+// (r, s) pair, which must be converted to the fixed-width raw r||s
+// concatenation RFC 6605 requires before it can go in an RRSIG record.
+func (k *kmsPrivateKey) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	algorithm, ecCurveSize, err := k.signingAlgorithm()
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := k.client.Sign(&kms.SignInput{
+		KeyId:            aws.String(k.keyID),
+		Message:          digest,
+		MessageType:      aws.String(kms.MessageTypeDigest),
+		SigningAlgorithm: aws.String(algorithm),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("KMSKeyStore: kms.Sign: %s", err)
+	}
+
+	if ecCurveSize == 0 {
+		return out.Signature, nil
+	}
+
+	var sig asn1EcdsaSignature
+	if _, err := asn1.Unmarshal(out.Signature, &sig); err != nil {
+		return nil, fmt.Errorf("KMSKeyStore: parsing KMS ECDSA signature: %s", err)
+	}
+	return append(padLeft(sig.R.Bytes(), ecCurveSize), padLeft(sig.S.Bytes(), ecCurveSize)...), nil
+}
+
+// signingAlgorithm returns the KMS SigningAlgorithmSpec to use for k's
+// public key, and (for ECDSA) the curve's field size in bytes so Sign knows
+// how wide to pad r and s.
+func (k *kmsPrivateKey) signingAlgorithm() (algorithm string, ecCurveSize int, err error) {
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		return kms.SigningAlgorithmSpecRsassaPkcs1V15Sha256, 0, nil
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		return kms.SigningAlgorithmSpecEcdsaSha256, size, nil
+	default:
+		return "", 0, fmt.Errorf("KMSKeyStore: unsupported key type %T for KMS signing", k.public)
+	}
+}
@@ -0,0 +1,155 @@
+package cli53
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/miekg/dns"
+)
+
+// Route53DNSSECManager drives Route53's native DNSSEC signing: the KSK is
+// held in KMS and referenced by ARN, while Route53 itself generates and
+// rotates the ZSK and signs every RRset. This is the API cli53 dnssec must
+// use against the default provider, because Route53 rejects customer-
+// supplied DNSKEY/RRSIG/NSEC record types pushed through ApplyChanges with
+// InvalidChangeBatch - Signer/KeyStore's self-signing path (signing RRsets
+// locally and UPSERTing the results as ordinary records) only works against
+// backends that accept arbitrary record types, such as BindFileProvider.
+type Route53DNSSECManager struct {
+	r53 *route53.Route53
+}
+
+// NewRoute53DNSSECManager wraps an existing route53.Route53 client.
+func NewRoute53DNSSECManager(r53 *route53.Route53) *Route53DNSSECManager {
+	return &Route53DNSSECManager{r53: r53}
+}
+
+// kskName is the Route53 key-signing key name cli53 creates and manages.
+// Route53 scopes KSK names to the hosted zone, so reusing one name across
+// zones is fine.
+const kskName = "cli53"
+
+// Enable creates a key-signing key backed by the KMS CMK at kmsKeyArn and
+// turns on Route53-managed signing for zoneID, returning the DS records to
+// publish at the parent zone/registrar.
+func (m *Route53DNSSECManager) Enable(zoneID, kmsKeyArn string) ([]*dns.DS, error) {
+	ref := uniqueReference()
+	_, err := m.r53.CreateKeySigningKey(&route53.CreateKeySigningKeyInput{
+		CallerReference:         aws.String(ref),
+		HostedZoneId:            aws.String(zoneID),
+		KeyManagementServiceArn: aws.String(kmsKeyArn),
+		Name:                    aws.String(kskName),
+		Status:                  aws.String("ACTIVE"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating key-signing key: %s", err)
+	}
+
+	if _, err := m.r53.EnableHostedZoneDNSSEC(&route53.EnableHostedZoneDNSSECInput{
+		HostedZoneId: aws.String(zoneID),
+	}); err != nil {
+		return nil, fmt.Errorf("enabling DNSSEC: %s", err)
+	}
+
+	return m.ShowDS(zoneID)
+}
+
+// Disable turns off Route53-managed signing and removes every key-signing
+// key cli53 created for zoneID.
+func (m *Route53DNSSECManager) Disable(zoneID string) error {
+	if _, err := m.r53.DisableHostedZoneDNSSEC(&route53.DisableHostedZoneDNSSECInput{
+		HostedZoneId: aws.String(zoneID),
+	}); err != nil {
+		return fmt.Errorf("disabling DNSSEC: %s", err)
+	}
+	return m.deleteKeySigningKeys(zoneID)
+}
+
+// RotateKSK creates a fresh key-signing key backed by kmsKeyArn and retires
+// every previous one. Route53 manages the ZSK internally once native
+// signing is enabled, so the KSK is the only customer-held key left to
+// rotate; cli53's rotate-zsk subcommand predates Route53-managed signing
+// and keeps its name for backwards compatibility, but against this
+// provider it rotates the KSK.
+func (m *Route53DNSSECManager) RotateKSK(zoneID, kmsKeyArn string) ([]*dns.DS, error) {
+	out, err := m.r53.GetDNSSEC(&route53.GetDNSSECInput{HostedZoneId: aws.String(zoneID)})
+	if err != nil {
+		return nil, fmt.Errorf("fetching existing key-signing keys: %s", err)
+	}
+	previous := out.KeySigningKeys
+
+	ref := uniqueReference()
+	_, err = m.r53.CreateKeySigningKey(&route53.CreateKeySigningKeyInput{
+		CallerReference:         aws.String(ref),
+		HostedZoneId:            aws.String(zoneID),
+		KeyManagementServiceArn: aws.String(kmsKeyArn),
+		Name:                    aws.String(kskName + "-" + ref[:8]),
+		Status:                  aws.String("ACTIVE"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating replacement key-signing key: %s", err)
+	}
+
+	for _, ksk := range previous {
+		if err := m.retireKeySigningKey(zoneID, aws.StringValue(ksk.Name)); err != nil {
+			return nil, err
+		}
+	}
+
+	return m.ShowDS(zoneID)
+}
+
+// ShowDS returns the DS records for every active key-signing key on
+// zoneID, for publishing at the parent zone/registrar.
+func (m *Route53DNSSECManager) ShowDS(zoneID string) ([]*dns.DS, error) {
+	out, err := m.r53.GetDNSSEC(&route53.GetDNSSECInput{HostedZoneId: aws.String(zoneID)})
+	if err != nil {
+		return nil, fmt.Errorf("fetching DS records: %s", err)
+	}
+
+	var records []*dns.DS
+	for _, ksk := range out.KeySigningKeys {
+		rr, err := dns.NewRR(aws.StringValue(ksk.DSRecord))
+		if err != nil {
+			return nil, fmt.Errorf("parsing DS record for %s: %s", aws.StringValue(ksk.Name), err)
+		}
+		ds, ok := rr.(*dns.DS)
+		if !ok {
+			return nil, fmt.Errorf("%s's DSRecord did not parse as a DS record", aws.StringValue(ksk.Name))
+		}
+		records = append(records, ds)
+	}
+	return records, nil
+}
+
+func (m *Route53DNSSECManager) deleteKeySigningKeys(zoneID string) error {
+	out, err := m.r53.GetDNSSEC(&route53.GetDNSSECInput{HostedZoneId: aws.String(zoneID)})
+	if err != nil {
+		return fmt.Errorf("fetching key-signing keys: %s", err)
+	}
+	for _, ksk := range out.KeySigningKeys {
+		if err := m.retireKeySigningKey(zoneID, aws.StringValue(ksk.Name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retireKeySigningKey deactivates then deletes a key-signing key. Route53
+// only allows deleting an inactive key.
+func (m *Route53DNSSECManager) retireKeySigningKey(zoneID, name string) error {
+	if _, err := m.r53.DeactivateKeySigningKey(&route53.DeactivateKeySigningKeyInput{
+		HostedZoneId: aws.String(zoneID),
+		Name:         aws.String(name),
+	}); err != nil {
+		return fmt.Errorf("deactivating key-signing key %s: %s", name, err)
+	}
+	if _, err := m.r53.DeleteKeySigningKey(&route53.DeleteKeySigningKeyInput{
+		HostedZoneId: aws.String(zoneID),
+		Name:         aws.String(name),
+	}); err != nil {
+		return fmt.Errorf("deleting key-signing key %s: %s", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package cli53
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Route53's own limits on a single ChangeResourceRecordSets request.
+const (
+	maxChangesPerBatch = 1000
+	maxBatchChars      = 32000
+)
+
+// EventType identifies what a ProgressEvent reports.
+type EventType int
+
+const (
+	BatchSubmitted EventType = iota
+	BatchInSync
+	BatchFailed
+	BatchRolledBack
+)
+
+// ProgressEvent is streamed from ChangeExecutor.Run over its events channel
+// so a CLI can render a progress bar without polling the executor directly.
+type ProgressEvent struct {
+	Type  EventType
+	Batch int // index into the sharded batches (for BatchRolledBack, into the committed batches being rolled back)
+	Total int // total number of batches (for BatchRolledBack, of committed batches)
+	Err   error
+}
+
+// throttleCodes are the Route53 error codes a submission can fail with when
+// the account is being rate limited, distinct from transient network
+// errors the AWS SDK's own retrier already handles.
+var throttleCodes = map[string]bool{
+	"Throttling":              true,
+	"ThrottlingException":     true,
+	"PriorRequestNotComplete": true,
+}
+
+func isThrottled(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok {
+		return throttleCodes[aerr.Code()]
+	}
+	return false
+}
+
+// ChangeExecutor shards an arbitrary change set into Route53-sized batches,
+// submits N of them concurrently, and polls each submitted batch's change
+// until it reaches INSYNC. If any batch ultimately fails (including after
+// exhausting throttle retries), Run stops submitting further batches and
+// rolls back the ones that already succeeded.
+type ChangeExecutor struct {
+	R53 *route53.Route53
+
+	// MaxInflight bounds how many batches are submitted concurrently. 0
+	// means 1 (sequential, matching the old cleanupDomain behaviour).
+	MaxInflight int
+
+	// PollInterval is the initial delay between GetChange polls; it backs
+	// off exponentially up to PollMaxInterval.
+	PollInterval    time.Duration
+	PollMaxInterval time.Duration
+
+	// MaxPollAttempts bounds how many times Run polls a single change
+	// before giving up on it ever reaching INSYNC. 0 means 20.
+	MaxPollAttempts int
+
+	// MaxSubmitRetries bounds how many times a throttled submission is
+	// retried before it's treated as a failure. This is orchestration-level
+	// retry on top of (not instead of) the AWS SDK's own request retries,
+	// for throttling that surfaces as a failed ChangeResourceRecordSets
+	// call rather than a retryable transport error. 0 means 5.
+	MaxSubmitRetries int
+
+	// SubmitRetryBackoff is the base delay between throttle retries; it
+	// backs off exponentially per attempt. 0 means 2s.
+	SubmitRetryBackoff time.Duration
+}
+
+// NewChangeExecutor returns a ChangeExecutor with sane defaults: one
+// in-flight batch, a 1s-to-30s exponential polling backoff, 20 poll
+// attempts, and 5 throttle retries with a 2s base backoff.
+func NewChangeExecutor(r53 *route53.Route53) *ChangeExecutor {
+	return &ChangeExecutor{
+		R53:                r53,
+		MaxInflight:        1,
+		PollInterval:       time.Second,
+		PollMaxInterval:    30 * time.Second,
+		MaxPollAttempts:    20,
+		MaxSubmitRetries:   5,
+		SubmitRetryBackoff: 2 * time.Second,
+	}
+}
+
+// Shard splits changes into batches that each respect Route53's 1000-change
+// and 32000-character limits.
+func Shard(changes []*route53.Change) [][]*route53.Change {
+	var batches [][]*route53.Change
+	var current []*route53.Change
+	currentChars := 0
+
+	for _, change := range changes {
+		chars := changeSize(change)
+		if len(current) > 0 && (len(current)+1 > maxChangesPerBatch || currentChars+chars > maxBatchChars) {
+			batches = append(batches, current)
+			current = nil
+			currentChars = 0
+		}
+		current = append(current, change)
+		currentChars += chars
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+	return batches
+}
+
+// changeSize estimates the character cost Route53 charges a change against
+// the 32000-character batch limit: the record name plus every value.
+func changeSize(c *route53.Change) int {
+	size := len(aws.StringValue(c.ResourceRecordSet.Name))
+	for _, rr := range c.ResourceRecordSet.ResourceRecords {
+		size += len(aws.StringValue(rr.Value))
+	}
+	return size
+}
+
+// invertChange returns the change that would undo c, for rollback. UPSERT
+// has no safe generic inverse without a snapshot of the value it replaced,
+// so it is not invertible here.
+func invertChange(c *route53.Change) (*route53.Change, bool) {
+	switch aws.StringValue(c.Action) {
+	case "CREATE":
+		return &route53.Change{Action: aws.String("DELETE"), ResourceRecordSet: c.ResourceRecordSet}, true
+	case "DELETE":
+		return &route53.Change{Action: aws.String("CREATE"), ResourceRecordSet: c.ResourceRecordSet}, true
+	default:
+		return nil, false
+	}
+}
+
+// Run shards changes, submits batches up to MaxInflight concurrently against
+// zoneID, and blocks until every batch is INSYNC or the run is aborted,
+// streaming ProgressEvents as it goes. The caller should range over events
+// until Run returns; events is closed when Run returns.
+//
+// If a batch fails (including after exhausting its throttle retries), Run
+// stops submitting batches that haven't started yet and rolls back the
+// CREATE/DELETE changes of batches that already reached INSYNC; UPSERTs are
+// left in place since they can't be safely inverted, and a BatchRolledBack
+// event with a non-nil Err marks that case so the caller can surface it.
+func (e *ChangeExecutor) Run(zoneID string, changes []*route53.Change, events chan<- ProgressEvent) error {
+	defer close(events)
+
+	batches := Shard(changes)
+	if len(batches) == 0 {
+		return nil
+	}
+
+	maxInflight := e.MaxInflight
+	if maxInflight <= 0 {
+		maxInflight = 1
+	}
+
+	sem := make(chan struct{}, maxInflight)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	var aborted int32
+	committed := make([][]*route53.Change, 0, len(batches))
+
+	for i, batch := range batches {
+		if atomic.LoadInt32(&aborted) != 0 {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int, batch []*route53.Change) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if atomic.LoadInt32(&aborted) != 0 {
+				return
+			}
+
+			err := e.runBatch(zoneID, i, len(batches), batch, events)
+			mu.Lock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				atomic.StoreInt32(&aborted, 1)
+			} else {
+				committed = append(committed, batch)
+			}
+			mu.Unlock()
+		}(i, batch)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		e.rollback(zoneID, committed, events)
+		return firstErr
+	}
+	return nil
+}
+
+// rollback submits the inverse of every committed batch, best-effort, and
+// streams a BatchRolledBack event per batch (with Err set if that batch
+// couldn't be fully inverted).
+func (e *ChangeExecutor) rollback(zoneID string, committed [][]*route53.Change, events chan<- ProgressEvent) {
+	for i, batch := range committed {
+		var inverse []*route53.Change
+		skipped := 0
+		for _, c := range batch {
+			if inv, ok := invertChange(c); ok {
+				inverse = append(inverse, inv)
+			} else {
+				skipped++
+			}
+		}
+
+		var err error
+		if len(inverse) > 0 {
+			_, err = e.submitWithRetry(zoneID, inverse)
+		}
+		if skipped > 0 && err == nil {
+			err = fmt.Errorf("%d UPSERT change(s) left in place; no prior value to restore", skipped)
+		}
+		events <- ProgressEvent{Type: BatchRolledBack, Batch: i, Total: len(committed), Err: err}
+	}
+}
+
+func (e *ChangeExecutor) runBatch(zoneID string, i, total int, batch []*route53.Change, events chan<- ProgressEvent) error {
+	resp, err := e.submitWithRetry(zoneID, batch)
+	if err != nil {
+		events <- ProgressEvent{Type: BatchFailed, Batch: i, Total: total, Err: err}
+		return err
+	}
+	events <- ProgressEvent{Type: BatchSubmitted, Batch: i, Total: total}
+
+	if err := e.waitUntilInSync(*resp.ChangeInfo.Id); err != nil {
+		events <- ProgressEvent{Type: BatchFailed, Batch: i, Total: total, Err: err}
+		return err
+	}
+	events <- ProgressEvent{Type: BatchInSync, Batch: i, Total: total}
+	return nil
+}
+
+// submitWithRetry calls ChangeResourceRecordSets, retrying on Route53
+// throttling responses up to MaxSubmitRetries times with exponential
+// backoff. This is separate from (and on top of) the AWS SDK client's own
+// transport-level retries.
+func (e *ChangeExecutor) submitWithRetry(zoneID string, batch []*route53.Change) (*route53.ChangeResourceRecordSetsOutput, error) {
+	maxRetries := e.MaxSubmitRetries
+	if maxRetries <= 0 {
+		maxRetries = 5
+	}
+	backoff := e.SubmitRetryBackoff
+	if backoff <= 0 {
+		backoff = 2 * time.Second
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		resp, err := e.R53.ChangeResourceRecordSets(&route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: &zoneID,
+			ChangeBatch:  &route53.ChangeBatch{Changes: batch},
+		})
+		if err == nil {
+			return resp, nil
+		}
+		lastErr = err
+		if !isThrottled(err) || attempt == maxRetries {
+			return nil, err
+		}
+		time.Sleep(backoff << uint(attempt))
+	}
+	return nil, lastErr
+}
+
+func (e *ChangeExecutor) waitUntilInSync(changeID string) error {
+	delay := e.PollInterval
+	if delay <= 0 {
+		delay = time.Second
+	}
+	maxDelay := e.PollMaxInterval
+	if maxDelay <= 0 {
+		maxDelay = 30 * time.Second
+	}
+	maxAttempts := e.MaxPollAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 20
+	}
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		resp, err := e.R53.GetChange(&route53.GetChangeInput{Id: &changeID})
+		if err != nil {
+			return fmt.Errorf("polling change %s: %s", changeID, err)
+		}
+		if aws.StringValue(resp.ChangeInfo.Status) == "INSYNC" {
+			return nil
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+		if delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+	return fmt.Errorf("change %s did not reach INSYNC after %d polls", changeID, maxAttempts)
+}
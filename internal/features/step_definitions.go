@@ -14,6 +14,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/route53"
 	"github.com/barnybug/cli53"
+	"github.com/miekg/dns"
 
 	. "github.com/lsegal/gucumber"
 )
@@ -25,6 +26,21 @@ func getService() *route53.Route53 {
 	return route53.New(&config)
 }
 
+// providerName returns the provider under test, set by "Given I am using
+// provider" or "route53" by default so existing scenarios are unaffected.
+func providerName() string {
+	if p, ok := World["$provider"]; ok {
+		return p.(string)
+	}
+	return "route53"
+}
+
+func getProvider() cli53.Provider {
+	p, err := cli53.NewProvider(providerName(), getService())
+	fatalIfErr(err)
+	return p
+}
+
 func fatalIfErr(err error) {
 	if err != nil {
 		log.Fatalf("Unexpected error: %s", err)
@@ -33,18 +49,18 @@ func fatalIfErr(err error) {
 
 var cleanupIds = []string{}
 var runOutput string
+var runDuration time.Duration
 
 func domainExists(name string) bool {
 	return domainId(name) != ""
 }
 
 func domainId(name string) string {
-	r53 := getService()
-	zones, err := r53.ListHostedZones(nil)
+	zones, err := getProvider().ListZones()
 	fatalIfErr(err)
-	for _, zone := range zones.HostedZones {
-		if *zone.Name == name+"." {
-			return *zone.Id
+	for _, zone := range zones {
+		if zone.Name == name+"." {
+			return zone.ID
 		}
 	}
 	return ""
@@ -59,9 +75,9 @@ func uniqueReference() string {
 	return fmt.Sprintf("%0x", rand.Int())
 }
 
-func cleanupDomain(r53 *route53.Route53, id string) {
+func cleanupDomain(provider cli53.Provider, id string) {
 	// delete all non-default SOA/NS records
-	rrsets, err := cli53.ListAllRecordSets(r53, id)
+	rrsets, err := provider.ListRecords(id)
 	fatalIfErr(err)
 	changes := []*route53.Change{}
 	for _, rrset := range rrsets {
@@ -75,21 +91,12 @@ func cleanupDomain(r53 *route53.Route53, id string) {
 	}
 
 	if len(changes) > 0 {
-		req2 := route53.ChangeResourceRecordSetsInput{
-			HostedZoneId: &id,
-			ChangeBatch: &route53.ChangeBatch{
-				Changes: changes,
-			},
-		}
-		_, err = r53.ChangeResourceRecordSets(&req2)
-		if err != nil {
+		if err := provider.ApplyChanges(id, changes); err != nil {
 			fmt.Printf("Warning: cleanup failed - %s\n", err)
 		}
 	}
 
-	req3 := route53.DeleteHostedZoneInput{Id: &id}
-	_, err = r53.DeleteHostedZone(&req3)
-	if err != nil {
+	if err := provider.DeleteZone(id); err != nil {
 		fmt.Printf("Warning: cleanup failed - %s\n", err)
 	}
 }
@@ -138,33 +145,33 @@ func init() {
 	After("", func() {
 		if len(cleanupIds) > 0 {
 			// cleanup
-			r53 := getService()
+			provider := getProvider()
 			for _, id := range cleanupIds {
-				cleanupDomain(r53, id)
+				cleanupDomain(provider, id)
 			}
 			cleanupIds = []string{}
 		}
 	})
 
+	Given(`^I am using provider "(.+?)"$`, func(name string) {
+		World["$provider"] = name
+	})
+
 	Given(`^I have a domain "(.+?)"$`, func(name string) {
 		name = domain(name)
 		// create a test domain
-		r53 := getService()
-		callerReference := uniqueReference()
-		req := route53.CreateHostedZoneInput{
-			CallerReference: &callerReference,
-			Name:            &name,
-		}
-		resp, err := r53.CreateHostedZone(&req)
+		zone, err := getProvider().CreateZone(name)
 		fatalIfErr(err)
-		cleanupIds = append(cleanupIds, *resp.HostedZone.Id)
+		cleanupIds = append(cleanupIds, zone.ID)
 	})
 
 	When(`^I run "(.+?)"$`, func(cmd string) {
 		cmd = domain(cmd)
 		args := safeSplit(cmd)
 		ps := exec.Command("./"+args[0], args[1:]...)
+		start := time.Now()
 		out, err := ps.CombinedOutput()
+		runDuration = time.Since(start)
 		if err != nil {
 			T.Errorf("Error: %s Output: %s", err, out)
 		} else {
@@ -195,9 +202,8 @@ func init() {
 
 	Then(`^the domain "(.+?)" has (\d+) records$`, func(name string, expected int) {
 		name = domain(name)
-		r53 := getService()
 		id := domainId(name)
-		rrsets, err := cli53.ListAllRecordSets(r53, id)
+		rrsets, err := getProvider().ListRecords(id)
 		fatalIfErr(err)
 		actual := len(rrsets)
 		if expected != actual {
@@ -247,12 +253,50 @@ func init() {
 			T.Errorf("Output did not contain \"%s\"", s)
 		}
 	})
+
+	Then(`^the apply reports (\d+) changes?$`, func(expected int) {
+		actual := countApplyChanges(runOutput)
+		if actual != expected {
+			T.Errorf("Expected %d changes, apply output reported %d:\n%s", expected, actual, runOutput)
+		}
+	})
+
+	Then(`^the domain "(.+?)" has a valid DNSSEC chain$`, func(name string) {
+		name = domain(name)
+		if !hasValidRRSIGChain(name) {
+			T.Errorf("Domain %s: DNSSEC chain did not validate", name)
+		}
+	})
+
+	Then(`^it completed within (\d+) seconds$`, func(maxSeconds int) {
+		if runDuration > time.Duration(maxSeconds)*time.Second {
+			T.Errorf("Expected completion within %ds, took %s", maxSeconds, runDuration)
+		}
+	})
+}
+
+// countApplyChanges counts the "+ ", "- " and "~ " lines that cli53 apply
+// --dry-run prints (see cli53.DiffText), one per changed BIND record.
+func countApplyChanges(output string) int {
+	count := 0
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "+ ") || strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "~ ") {
+			count++
+		}
+	}
+	return count
 }
 
 func hasRecord(name, record string) bool {
-	r53 := getService()
+	return hasRecordValidated(name, record, false)
+}
+
+// hasRecordValidated is hasRecord extended to optionally require that the
+// matching RRset carries an RRSIG that verifies against the zone's
+// published DNSKEY, for DNSSEC scenarios.
+func hasRecordValidated(name, record string, requireValidRRSIG bool) bool {
 	id := domainId(name)
-	rrsets, err := cli53.ListAllRecordSets(r53, id)
+	rrsets, err := getProvider().ListRecords(id)
 	fatalIfErr(err)
 
 	for _, rrset := range rrsets {
@@ -261,6 +305,9 @@ func hasRecord(name, record string) bool {
 			line := rr.String()
 			line = strings.Replace(line, "\t", " ", -1)
 			if record == line {
+				if requireValidRRSIG && !rrsetHasValidRRSIG(name, rrsets, rrset) {
+					continue
+				}
 				return true
 			}
 		}
@@ -268,6 +315,66 @@ func hasRecord(name, record string) bool {
 	return false
 }
 
+// hasValidRRSIGChain reports whether every non-apex-managed RRset in the
+// zone has a verifying RRSIG, i.e. that cli53 dnssec enable fully signed it.
+func hasValidRRSIGChain(name string) bool {
+	id := domainId(name)
+	rrsets, err := getProvider().ListRecords(id)
+	fatalIfErr(err)
+
+	for _, rrset := range rrsets {
+		if *rrset.Type == "NS" || *rrset.Type == "SOA" || *rrset.Type == "RRSIG" {
+			continue
+		}
+		if !rrsetHasValidRRSIG(name, rrsets, rrset) {
+			return false
+		}
+	}
+	return true
+}
+
+// rrsetHasValidRRSIG finds the DNSKEY and a matching RRSIG among rrsets and
+// verifies rrset against it.
+func rrsetHasValidRRSIG(name string, rrsets []*route53.ResourceRecordSet, rrset *route53.ResourceRecordSet) bool {
+	var dnskeys []*dns.DNSKEY
+	var dnskey *dns.DNSKEY
+	var rrsig *dns.RRSIG
+	var rrs []dns.RR
+
+	for _, rr := range cli53.ConvertRRSetToBind(rrset) {
+		rrs = append(rrs, rr)
+	}
+
+	for _, candidate := range rrsets {
+		if *candidate.Type == "DNSKEY" {
+			for _, rr := range cli53.ConvertRRSetToBind(candidate) {
+				if k, ok := rr.(*dns.DNSKEY); ok {
+					dnskeys = append(dnskeys, k)
+				}
+			}
+		}
+		if *candidate.Type == "RRSIG" && *candidate.Name == *rrset.Name {
+			for _, rr := range cli53.ConvertRRSetToBind(candidate) {
+				if s, ok := rr.(*dns.RRSIG); ok && s.TypeCovered == rrs[0].Header().Rrtype {
+					rrsig = s
+				}
+			}
+		}
+	}
+
+	if rrsig != nil {
+		for _, k := range dnskeys {
+			if k.KeyTag() == rrsig.KeyTag {
+				dnskey = k
+			}
+		}
+	}
+	if dnskey == nil || rrsig == nil {
+		return false
+	}
+	return rrsig.Verify(dnskey, rrs) == nil
+}
+
 func prepareZoneFile(b []byte, includeAuth bool) map[string]bool {
 	s := string(b)
 	s = strings.Replace(s, "\t", " ", -1)
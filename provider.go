@@ -0,0 +1,168 @@
+package cli53
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// Zone is a provider-agnostic handle for a hosted/managed zone: just enough
+// to list, target and delete it without assuming Route53's HostedZone shape.
+type Zone struct {
+	ID   string
+	Name string
+}
+
+// Provider is the interface cli53 talks to for all zone and record
+// operations. Route53Provider is the default; other backends let cli53
+// manage zones hosted elsewhere using the same commands.
+type Provider interface {
+	// ListZones returns every zone the provider can see.
+	ListZones() ([]Zone, error)
+
+	// ListRecords returns every record set in the given zone.
+	ListRecords(zoneID string) ([]*route53.ResourceRecordSet, error)
+
+	// ApplyChanges submits a batch of creates/updates/deletes for the zone.
+	ApplyChanges(zoneID string, changes []*route53.Change) error
+
+	// CreateZone creates a new zone and returns its handle.
+	CreateZone(name string) (Zone, error)
+
+	// DeleteZone removes a zone entirely.
+	DeleteZone(zoneID string) error
+}
+
+// Route53Provider is the default Provider, backed by Amazon Route53.
+type Route53Provider struct {
+	r53 *route53.Route53
+
+	// MaxInflight bounds how many change batches ApplyChanges submits to
+	// Route53 concurrently; see ChangeExecutor. 0 means 1 (sequential).
+	MaxInflight int
+
+	// OnProgress, if set, receives each ProgressEvent ApplyChanges's
+	// ChangeExecutor emits, e.g. to render a progress bar.
+	OnProgress func(ProgressEvent)
+}
+
+// NewRoute53Provider wraps an existing route53.Route53 client.
+func NewRoute53Provider(r53 *route53.Route53) *Route53Provider {
+	return &Route53Provider{r53: r53}
+}
+
+func (p *Route53Provider) ListZones() ([]Zone, error) {
+	resp, err := p.r53.ListHostedZones(nil)
+	if err != nil {
+		return nil, err
+	}
+	zones := make([]Zone, 0, len(resp.HostedZones))
+	for _, z := range resp.HostedZones {
+		zones = append(zones, Zone{ID: aws.StringValue(z.Id), Name: aws.StringValue(z.Name)})
+	}
+	return zones, nil
+}
+
+func (p *Route53Provider) ListRecords(zoneID string) ([]*route53.ResourceRecordSet, error) {
+	return ListAllRecordSets(p.r53, zoneID)
+}
+
+func (p *Route53Provider) ApplyChanges(zoneID string, changes []*route53.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	executor := NewChangeExecutor(p.r53)
+	executor.MaxInflight = p.MaxInflight
+
+	events := make(chan ProgressEvent)
+	done := make(chan error, 1)
+	go func() { done <- executor.Run(zoneID, changes, events) }()
+
+	for event := range events {
+		if p.OnProgress != nil {
+			p.OnProgress(event)
+		}
+	}
+	return <-done
+}
+
+func (p *Route53Provider) CreateZone(name string) (Zone, error) {
+	ref := uniqueReference()
+	resp, err := p.r53.CreateHostedZone(&route53.CreateHostedZoneInput{
+		CallerReference: &ref,
+		Name:            &name,
+	})
+	if err != nil {
+		return Zone{}, err
+	}
+	return Zone{ID: aws.StringValue(resp.HostedZone.Id), Name: aws.StringValue(resp.HostedZone.Name)}, nil
+}
+
+func (p *Route53Provider) DeleteZone(zoneID string) error {
+	_, err := p.r53.DeleteHostedZone(&route53.DeleteHostedZoneInput{Id: &zoneID})
+	return err
+}
+
+// DNSSECManager returns the Route53-native DNSSEC manager for this
+// provider's client. cli53 dnssec uses it instead of Signer's self-signing
+// path, since Route53 rejects customer-supplied DNSKEY/RRSIG records.
+func (p *Route53Provider) DNSSECManager() *Route53DNSSECManager {
+	return NewRoute53DNSSECManager(p.r53)
+}
+
+// uniqueReference returns a fresh CallerReference on every call. Route53
+// treats CreateHostedZone as idempotent on CallerReference, so reusing one
+// value for the life of the process (e.g. the PID) makes every call after
+// the first in a run return the first call's zone instead of creating a
+// new one.
+func uniqueReference() string {
+	b := make([]byte, 16)
+	if _, err := cryptorand.Read(b); err != nil {
+		// cryptorand.Read only fails if the OS entropy source is broken;
+		// PID+pointer-ish fallback still beats a constant reference.
+		return fmt.Sprintf("%x-%p", os.Getpid(), &b)
+	}
+	return hex.EncodeToString(b)
+}
+
+// ProviderConfig resolves which Provider to use, analogous to how
+// dnscontrol's creds.json registers provider credentials by name. Lookup
+// order: the --provider flag value, then the CLI53_PROVIDER environment
+// variable, then "route53".
+type ProviderConfig struct {
+	// Flag is the value of an explicit --provider flag, if given.
+	Flag string
+}
+
+// Resolve returns the provider name to use, applying the documented
+// precedence.
+func (c ProviderConfig) Resolve() string {
+	if c.Flag != "" {
+		return c.Flag
+	}
+	if env := os.Getenv("CLI53_PROVIDER"); env != "" {
+		return env
+	}
+	return "route53"
+}
+
+// NewProvider constructs the named Provider. Unknown names are a
+// configuration error rather than falling back silently.
+func NewProvider(name string, r53 *route53.Route53) (Provider, error) {
+	switch name {
+	case "", "route53":
+		return NewRoute53Provider(r53), nil
+	case "cloudflare":
+		return NewCloudflareProvider()
+	case "gcdns":
+		return NewGoogleCloudDNSProvider()
+	case "bind":
+		return NewBindFileProvider(os.Getenv("CLI53_BIND_DIR"))
+	default:
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+}
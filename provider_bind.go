@@ -0,0 +1,100 @@
+package cli53
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+// BindFileProvider is a Provider backed by a directory of BIND zone files
+// on local disk, one "<zone-id>.zone" file per zone. It exists mainly for
+// testing cli53's diff/apply logic without talking to a real DNS backend.
+type BindFileProvider struct {
+	Dir string
+}
+
+// NewBindFileProvider returns a BindFileProvider rooted at dir, creating it
+// if necessary.
+func NewBindFileProvider(dir string) (*BindFileProvider, error) {
+	if dir == "" {
+		dir = "."
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &BindFileProvider{Dir: dir}, nil
+}
+
+func (p *BindFileProvider) zonePath(zoneID string) string {
+	return filepath.Join(p.Dir, zoneID+".zone")
+}
+
+func (p *BindFileProvider) ListZones() ([]Zone, error) {
+	files, err := ioutil.ReadDir(p.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var zones []Zone
+	for _, f := range files {
+		if !strings.HasSuffix(f.Name(), ".zone") {
+			continue
+		}
+		id := strings.TrimSuffix(f.Name(), ".zone")
+		zones = append(zones, Zone{ID: id, Name: id + "."})
+	}
+	return zones, nil
+}
+
+func (p *BindFileProvider) ListRecords(zoneID string) ([]*route53.ResourceRecordSet, error) {
+	f, err := os.Open(p.zonePath(zoneID))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseBindRecordSets(f, "", p.zonePath(zoneID))
+}
+
+func (p *BindFileProvider) ApplyChanges(zoneID string, changes []*route53.Change) error {
+	existing, err := p.ListRecords(zoneID)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	byKey := map[rrsetKey]*route53.ResourceRecordSet{}
+	for _, rrset := range existing {
+		byKey[keyFor(rrset)] = rrset
+	}
+	for _, change := range changes {
+		key := keyFor(change.ResourceRecordSet)
+		switch aws.StringValue(change.Action) {
+		case "DELETE":
+			delete(byKey, key)
+		default: // CREATE, UPSERT
+			byKey[key] = change.ResourceRecordSet
+		}
+	}
+
+	var b strings.Builder
+	for _, rrset := range byKey {
+		for _, rr := range ConvertRRSetToBind(rrset) {
+			fmt.Fprintln(&b, rr.String())
+		}
+	}
+	return ioutil.WriteFile(p.zonePath(zoneID), []byte(b.String()), 0644)
+}
+
+func (p *BindFileProvider) CreateZone(name string) (Zone, error) {
+	id := strings.TrimSuffix(name, ".")
+	if err := ioutil.WriteFile(p.zonePath(id), nil, 0644); err != nil {
+		return Zone{}, err
+	}
+	return Zone{ID: id, Name: name}, nil
+}
+
+func (p *BindFileProvider) DeleteZone(zoneID string) error {
+	return os.Remove(p.zonePath(zoneID))
+}
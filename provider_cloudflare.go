@@ -0,0 +1,232 @@
+package cli53
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+const cloudflareAPIBase = "https://api.cloudflare.com/client/v4"
+
+// CloudflareProvider manages zones hosted on Cloudflare via its REST API
+// (https://developers.cloudflare.com/api/), using only net/http since
+// Cloudflare's official Go SDK isn't vendored in this module.
+type CloudflareProvider struct {
+	APIToken string
+	client   *http.Client
+}
+
+// NewCloudflareProvider reads CF_API_TOKEN from the environment, the same
+// variable name Cloudflare's own tooling (flarectl, terraform-provider-cloudflare)
+// uses.
+func NewCloudflareProvider() (*CloudflareProvider, error) {
+	token := os.Getenv("CF_API_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("cloudflare provider: CF_API_TOKEN is not set")
+	}
+	return &CloudflareProvider{APIToken: token, client: http.DefaultClient}, nil
+}
+
+type cfEnvelope struct {
+	Success bool            `json:"success"`
+	Errors  []cfError       `json:"errors"`
+	Result  json.RawMessage `json:"result"`
+}
+
+type cfError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e cfError) String() string { return fmt.Sprintf("%d: %s", e.Code, e.Message) }
+
+type cfZone struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type cfRecord struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+}
+
+func (p *CloudflareProvider) do(method, path string, body interface{}) (*cfEnvelope, error) {
+	var reader *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, cloudflareAPIBase+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.APIToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cloudflare: %s", err)
+	}
+	defer resp.Body.Close()
+
+	var env cfEnvelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return nil, fmt.Errorf("cloudflare: decoding response: %s", err)
+	}
+	if !env.Success {
+		msgs := make([]string, len(env.Errors))
+		for i, e := range env.Errors {
+			msgs[i] = e.String()
+		}
+		return nil, fmt.Errorf("cloudflare: %s", strings.Join(msgs, "; "))
+	}
+	return &env, nil
+}
+
+func (p *CloudflareProvider) ListZones() ([]Zone, error) {
+	env, err := p.do("GET", "/zones", nil)
+	if err != nil {
+		return nil, err
+	}
+	var cfZones []cfZone
+	if err := json.Unmarshal(env.Result, &cfZones); err != nil {
+		return nil, fmt.Errorf("cloudflare: %s", err)
+	}
+	zones := make([]Zone, len(cfZones))
+	for i, z := range cfZones {
+		zones[i] = Zone{ID: z.ID, Name: z.Name + "."}
+	}
+	return zones, nil
+}
+
+func (p *CloudflareProvider) listCFRecords(zoneID string) ([]cfRecord, error) {
+	env, err := p.do("GET", "/zones/"+zoneID+"/dns_records?per_page=5000", nil)
+	if err != nil {
+		return nil, err
+	}
+	var records []cfRecord
+	if err := json.Unmarshal(env.Result, &records); err != nil {
+		return nil, fmt.Errorf("cloudflare: %s", err)
+	}
+	return records, nil
+}
+
+// ListRecords groups Cloudflare's one-value-per-record model back into the
+// RRset shape the rest of cli53 works with.
+func (p *CloudflareProvider) ListRecords(zoneID string) ([]*route53.ResourceRecordSet, error) {
+	records, err := p.listCFRecords(zoneID)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := map[rrsetKey]*route53.ResourceRecordSet{}
+	var order []rrsetKey
+	for _, r := range records {
+		key := rrsetKey{Name: strings.ToLower(r.Name) + ".", Type: r.Type}
+		rrset, exists := grouped[key]
+		if !exists {
+			rrset = &route53.ResourceRecordSet{
+				Name: aws.String(r.Name + "."),
+				Type: aws.String(r.Type),
+				TTL:  aws.Int64(int64(r.TTL)),
+			}
+			grouped[key] = rrset
+			order = append(order, key)
+		}
+		rrset.ResourceRecords = append(rrset.ResourceRecords, &route53.ResourceRecord{Value: aws.String(r.Content)})
+	}
+
+	rrsets := make([]*route53.ResourceRecordSet, 0, len(order))
+	for _, key := range order {
+		rrsets = append(rrsets, grouped[key])
+	}
+	return rrsets, nil
+}
+
+// ApplyChanges translates each Change into Cloudflare's per-value DNS
+// record model: an RRset with N values becomes N individual records, so a
+// change is applied by diffing against Cloudflare's current records for
+// that (name, type) and creating/deleting the individual values that
+// differ.
+func (p *CloudflareProvider) ApplyChanges(zoneID string, changes []*route53.Change) error {
+	existing, err := p.listCFRecords(zoneID)
+	if err != nil {
+		return err
+	}
+	byNameType := map[rrsetKey][]cfRecord{}
+	for _, r := range existing {
+		key := rrsetKey{Name: strings.ToLower(r.Name) + ".", Type: r.Type}
+		byNameType[key] = append(byNameType[key], r)
+	}
+
+	for _, change := range changes {
+		rrset := change.ResourceRecordSet
+		key := keyFor(rrset)
+		name := strings.TrimSuffix(aws.StringValue(rrset.Name), ".")
+		typ := aws.StringValue(rrset.Type)
+		ttl := int(aws.Int64Value(rrset.TTL))
+
+		wanted := map[string]bool{}
+		if aws.StringValue(change.Action) != "DELETE" {
+			for _, rr := range rrset.ResourceRecords {
+				wanted[aws.StringValue(rr.Value)] = true
+			}
+		}
+
+		current := byNameType[key]
+		have := map[string]cfRecord{}
+		for _, r := range current {
+			have[r.Content] = r
+		}
+
+		for value := range wanted {
+			if _, exists := have[value]; !exists {
+				if _, err := p.do("POST", "/zones/"+zoneID+"/dns_records", cfRecord{
+					Type: typ, Name: name, Content: value, TTL: ttl,
+				}); err != nil {
+					return fmt.Errorf("cloudflare: creating %s %s %s: %s", name, typ, value, err)
+				}
+			}
+		}
+		for value, r := range have {
+			if !wanted[value] {
+				if _, err := p.do("DELETE", "/zones/"+zoneID+"/dns_records/"+r.ID, nil); err != nil {
+					return fmt.Errorf("cloudflare: deleting %s %s %s: %s", name, typ, value, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *CloudflareProvider) CreateZone(name string) (Zone, error) {
+	env, err := p.do("POST", "/zones", map[string]string{"name": strings.TrimSuffix(name, ".")})
+	if err != nil {
+		return Zone{}, err
+	}
+	var z cfZone
+	if err := json.Unmarshal(env.Result, &z); err != nil {
+		return Zone{}, fmt.Errorf("cloudflare: %s", err)
+	}
+	return Zone{ID: z.ID, Name: z.Name + "."}, nil
+}
+
+func (p *CloudflareProvider) DeleteZone(zoneID string) error {
+	_, err := p.do("DELETE", "/zones/"+zoneID, nil)
+	return err
+}
@@ -0,0 +1,216 @@
+package cli53
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/route53"
+)
+
+const gcdnsAPIBase = "https://dns.googleapis.com/dns/v1"
+
+// GoogleCloudDNSProvider manages zones hosted on Google Cloud DNS via its
+// REST API (https://cloud.google.com/dns/docs/reference/v1), using plain
+// net/http rather than Google's API client libraries, which aren't vendored
+// in this module. Callers are expected to obtain an OAuth2 access token
+// themselves (e.g. `gcloud auth print-access-token`) and pass it via
+// GOOGLE_OAUTH_TOKEN; this provider doesn't perform the OAuth2 dance itself.
+type GoogleCloudDNSProvider struct {
+	Project string
+	Token   string
+	client  *http.Client
+}
+
+// NewGoogleCloudDNSProvider reads GOOGLE_CLOUD_PROJECT and GOOGLE_OAUTH_TOKEN
+// from the environment.
+func NewGoogleCloudDNSProvider() (*GoogleCloudDNSProvider, error) {
+	project := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if project == "" {
+		return nil, fmt.Errorf("gcdns provider: GOOGLE_CLOUD_PROJECT is not set")
+	}
+	token := os.Getenv("GOOGLE_OAUTH_TOKEN")
+	if token == "" {
+		return nil, fmt.Errorf("gcdns provider: GOOGLE_OAUTH_TOKEN is not set")
+	}
+	return &GoogleCloudDNSProvider{Project: project, Token: token, client: http.DefaultClient}, nil
+}
+
+type gcdnsManagedZone struct {
+	ID      uint64 `json:"id,string"`
+	Name    string `json:"name"`
+	DNSName string `json:"dnsName"`
+}
+
+type gcdnsManagedZonesList struct {
+	ManagedZones []gcdnsManagedZone `json:"managedZones"`
+}
+
+type gcdnsRRSet struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	TTL     int64    `json:"ttl"`
+	Rrdatas []string `json:"rrdatas"`
+}
+
+type gcdnsRRSetList struct {
+	Rrsets []gcdnsRRSet `json:"rrsets"`
+}
+
+type gcdnsChange struct {
+	Additions []gcdnsRRSet `json:"additions,omitempty"`
+	Deletions []gcdnsRRSet `json:"deletions,omitempty"`
+}
+
+type gcdnsError struct {
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (p *GoogleCloudDNSProvider) do(method, path string, body interface{}, out interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, gcdnsAPIBase+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("gcdns: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var gerr gcdnsError
+		json.NewDecoder(resp.Body).Decode(&gerr)
+		if gerr.Error != nil && gerr.Error.Message != "" {
+			return fmt.Errorf("gcdns: %s", gerr.Error.Message)
+		}
+		return fmt.Errorf("gcdns: unexpected status %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (p *GoogleCloudDNSProvider) projectPath(suffix string) string {
+	return "/projects/" + p.Project + "/managedZones" + suffix
+}
+
+func (p *GoogleCloudDNSProvider) ListZones() ([]Zone, error) {
+	var list gcdnsManagedZonesList
+	if err := p.do("GET", p.projectPath(""), nil, &list); err != nil {
+		return nil, err
+	}
+	zones := make([]Zone, len(list.ManagedZones))
+	for i, z := range list.ManagedZones {
+		zones[i] = Zone{ID: z.Name, Name: z.DNSName}
+	}
+	return zones, nil
+}
+
+func (p *GoogleCloudDNSProvider) ListRecords(zoneID string) ([]*route53.ResourceRecordSet, error) {
+	var list gcdnsRRSetList
+	if err := p.do("GET", p.projectPath("/"+zoneID+"/rrsets"), nil, &list); err != nil {
+		return nil, err
+	}
+	rrsets := make([]*route53.ResourceRecordSet, len(list.Rrsets))
+	for i, rr := range list.Rrsets {
+		rrsets[i] = toRoute53RRSet(rr)
+	}
+	return rrsets, nil
+}
+
+// ApplyChanges submits changes as a single Cloud DNS "change" resource.
+// Cloud DNS's additions/deletions model requires the full prior rrset to
+// remove it, so UPSERT first looks up the rrset it's replacing.
+func (p *GoogleCloudDNSProvider) ApplyChanges(zoneID string, changes []*route53.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	existing, err := p.ListRecords(zoneID)
+	if err != nil {
+		return err
+	}
+	byKey := map[rrsetKey]*route53.ResourceRecordSet{}
+	for _, rrset := range existing {
+		byKey[keyFor(rrset)] = rrset
+	}
+
+	var change gcdnsChange
+	for _, c := range changes {
+		rrset := c.ResourceRecordSet
+		switch aws.StringValue(c.Action) {
+		case "CREATE":
+			change.Additions = append(change.Additions, toGCDNSRRSet(rrset))
+		case "DELETE":
+			change.Deletions = append(change.Deletions, toGCDNSRRSet(rrset))
+		case "UPSERT":
+			if old, ok := byKey[keyFor(rrset)]; ok {
+				change.Deletions = append(change.Deletions, toGCDNSRRSet(old))
+			}
+			change.Additions = append(change.Additions, toGCDNSRRSet(rrset))
+		}
+	}
+
+	return p.do("POST", p.projectPath("/"+zoneID+"/changes"), change, nil)
+}
+
+func (p *GoogleCloudDNSProvider) CreateZone(name string) (Zone, error) {
+	slug := strings.NewReplacer(".", "-").Replace(strings.TrimSuffix(name, "."))
+	input := gcdnsManagedZone{Name: slug, DNSName: name}
+	var created gcdnsManagedZone
+	if err := p.do("POST", p.projectPath(""), input, &created); err != nil {
+		return Zone{}, err
+	}
+	return Zone{ID: created.Name, Name: created.DNSName}, nil
+}
+
+func (p *GoogleCloudDNSProvider) DeleteZone(zoneID string) error {
+	return p.do("DELETE", p.projectPath("/"+zoneID), nil, nil)
+}
+
+func toGCDNSRRSet(rrset *route53.ResourceRecordSet) gcdnsRRSet {
+	values := make([]string, len(rrset.ResourceRecords))
+	for i, rr := range rrset.ResourceRecords {
+		values[i] = aws.StringValue(rr.Value)
+	}
+	return gcdnsRRSet{
+		Name:    aws.StringValue(rrset.Name),
+		Type:    aws.StringValue(rrset.Type),
+		TTL:     aws.Int64Value(rrset.TTL),
+		Rrdatas: values,
+	}
+}
+
+func toRoute53RRSet(rr gcdnsRRSet) *route53.ResourceRecordSet {
+	records := make([]*route53.ResourceRecord, len(rr.Rrdatas))
+	for i, v := range rr.Rrdatas {
+		records[i] = &route53.ResourceRecord{Value: aws.String(v)}
+	}
+	return &route53.ResourceRecordSet{
+		Name:            aws.String(rr.Name),
+		Type:            aws.String(rr.Type),
+		TTL:             aws.Int64(rr.TTL),
+		ResourceRecords: records,
+	}
+}